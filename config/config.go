@@ -0,0 +1,59 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// String returns the environment variable key, or def when it is unset
+func String(key, def string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	return value
+}
+
+// Int returns the environment variable key parsed as an integer, or def when it is unset or invalid
+func Int(key string, def int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default %d", value, key, def)
+		return def
+	}
+	return parsed
+}
+
+// Duration returns the environment variable key parsed as a duration such as "720h", or def when it is unset or invalid
+func Duration(key string, def time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default %s", value, key, def)
+		return def
+	}
+	return parsed
+}
+
+// Bool returns the environment variable key parsed as a boolean, or def when it is unset or invalid
+func Bool(key string, def bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default %t", value, key, def)
+		return def
+	}
+	return parsed
+}
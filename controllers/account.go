@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// ExportAccount godoc
+// @Summary Export my data
+// @Description Download a zip archive of everything stored about the authenticated user: export.json with their profile, announcements, the flags they filed and the status history of their announcements, and the same records as CSV files.
+// @Tags Users
+// @Produce application/zip
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {file} file "Zip archive"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 500 {object} utils.ErrorResponse "Could not export the data"
+// @Router /users/me/export [get]
+func (h *UserHandler) ExportAccount(context *gin.Context) {
+	userId := context.GetInt64("userId")
+	if _, err := h.Users.GetByID(context.Request.Context(), userId); err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	export, err := models.ExportUser(context.Request.Context(), h.Users, h.Announcements, h.Flags, userId)
+	if err != nil {
+		context.Error(fmt.Errorf("could not export the data: %w", err))
+		return
+	}
+	// Built in memory first so that a failure can still be reported as an error response
+	var archive bytes.Buffer
+	if err := export.WriteArchive(&archive); err != nil {
+		context.Error(fmt.Errorf("could not export the data: %w", err))
+		return
+	}
+
+	filename := fmt.Sprintf("announceit-export-%d-%s.zip", userId, export.ExportedAt.Format("20060102"))
+	context.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	context.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// DeleteAccount godoc
+// @Summary Delete my account
+// @Description Schedule the authenticated user's account for deletion after a grace period of ACCOUNT_DELETION_GRACE, 30 days by default, during which it keeps working and the deletion can be cancelled. Deletion erases the user's name, email, phone number and address; their announcements are kept for broadcast compliance.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.DeleteAccountRequest true "Current password"
+// @Success 202 {object} utils.AccountDeletionResponse "Account deletion scheduled"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid, or the password is incorrect"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 409 {object} utils.ErrorResponse "Account deletion is already scheduled, or the user is the last admin"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me [delete]
+func (h *UserHandler) DeleteAccount(context *gin.Context) {
+	var request models.DeleteAccountRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+
+	user, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	grace := config.Duration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+	err = user.ScheduleDeletion(context.Request.Context(), h.Users, request.Password, time.Now().Add(grace))
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		context.Error(models.Unauthorized("invalid_password", "Password is incorrect"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not schedule the deletion: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusAccepted, gin.H{"message": "Account deletion scheduled", "deletion_scheduled_at": user.DeletionScheduledAt})
+}
+
+// CancelAccountDeletion godoc
+// @Summary Cancel the deletion of my account
+// @Description Keep the authenticated user's account after scheduling its deletion, as long as the grace period has not ended.
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} utils.MessageResponse "Account deletion cancelled"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 409 {object} utils.ErrorResponse "No account deletion is scheduled"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/deletion/cancel [post]
+func (h *UserHandler) CancelAccountDeletion(context *gin.Context) {
+	err := h.Users.CancelDeletion(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not cancel the deletion: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountExportAndDeletion(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/login", userHandler.Login)
+	router.GET("/users/me/announcements", middlewares.Authenticate, announcementHandler.GetMyAnnouncements)
+	router.GET("/users/me/export", middlewares.Authenticate, userHandler.ExportAccount)
+	router.DELETE("/users/me", middlewares.Authenticate, userHandler.DeleteAccount)
+	router.POST("/users/me/deletion/cancel", middlewares.Authenticate, userHandler.CancelAccountDeletion)
+
+	db.TruncateUsersTable()
+	owner, ownerToken := createTestUser(t, "leaving@gmail.com", "+250781475600")
+	moderator, _ := createTestUser(t, "export-moderator@gmail.com", "+250781475601", models.RoleModerator)
+	mine := createTestAnnouncement(t, owner.ID, models.Pending)
+	_, err := mine.ChangeStatus(context.Background(), sqlAnnouncements, models.Accepted, moderator.ID, "Looks fine")
+	assert.NoError(t, err)
+	other := createTestAnnouncement(t, moderator.ID, models.Active)
+	flag := models.Flag{AnnouncementID: other.ID, UserID: owner.ID, Reason: models.FlagSpam, Description: "Again"}
+	assert.NoError(t, flag.Create(context.Background(), userHandler.Flags))
+
+	t.Run("Export my data", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, "/users/me/export", ownerToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "application/zip", resp.Header().Get("Content-Type"))
+		assert.Contains(t, resp.Header().Get("Content-Disposition"), "attachment")
+
+		archive, err := zip.NewReader(bytes.NewReader(resp.Body.Bytes()), int64(resp.Body.Len()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		files := map[string][]byte{}
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			assert.NoError(t, err)
+			files[file.Name], _ = io.ReadAll(reader)
+			reader.Close()
+		}
+		for _, name := range []string{"export.json", "profile.csv", "announcements.csv", "flags.csv", "status_history.csv"} {
+			assert.Contains(t, files, name)
+		}
+
+		var export models.UserExport
+		assert.NoError(t, json.Unmarshal(files["export.json"], &export))
+		assert.Equal(t, "leaving@gmail.com", export.Profile.Email)
+		assert.Empty(t, export.Profile.Password)
+		if assert.Len(t, export.Announcements, 1) {
+			assert.Equal(t, mine.ID, export.Announcements[0].ID)
+		}
+		if assert.Len(t, export.Flags, 1) {
+			assert.Equal(t, flag.ID, export.Flags[0].ID)
+		}
+		if assert.Len(t, export.StatusHistory, 1) {
+			assert.Equal(t, models.Accepted, export.StatusHistory[0].ToStatus)
+		}
+
+		records, err := csv.NewReader(bytes.NewReader(files["announcements.csv"])).ReadAll()
+		assert.NoError(t, err)
+		if assert.Len(t, records, 2) {
+			assert.Equal(t, "status", records[0][1])
+			assert.Equal(t, "accepted", records[1][1])
+		}
+		records, err = csv.NewReader(bytes.NewReader(files["profile.csv"])).ReadAll()
+		assert.NoError(t, err)
+		if assert.Len(t, records, 2) {
+			assert.Equal(t, "+250781475600", records[1][4])
+		}
+	})
+
+	t.Run("Deletion requires the password", func(t *testing.T) {
+		resp := testRequest(router, http.MethodDelete, "/users/me", ownerToken, `{}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+		resp = testRequest(router, http.MethodDelete, "/users/me", ownerToken, `{"password": "wrong"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Cancel a scheduled deletion", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/me/deletion/cancel", ownerToken, "")
+		assert.Equal(t, http.StatusConflict, resp.Code)
+
+		resp = testRequest(router, http.MethodDelete, "/users/me", ownerToken, `{"password": "1234"}`)
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		resp = testRequest(router, http.MethodDelete, "/users/me", ownerToken, `{"password": "1234"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+
+		// The account keeps working during the grace period
+		resp = testRequest(router, http.MethodGet, "/users/me/announcements", ownerToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = testRequest(router, http.MethodPost, "/users/me/deletion/cancel", ownerToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		anonymized, err := models.AnonymizeDueAccounts(context.Background(), sqlUsers, userHandler.Lockouts, time.Now().Add(365*24*time.Hour))
+		assert.NoError(t, err)
+		assert.Zero(t, anonymized)
+	})
+
+	t.Run("Anonymize the account after the grace period", func(t *testing.T) {
+		resp := testRequest(router, http.MethodDelete, "/users/me", ownerToken, `{"password": "1234"}`)
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		var body struct {
+			DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), body.DeletionScheduledAt, time.Minute)
+
+		anonymized, err := models.AnonymizeDueAccounts(context.Background(), sqlUsers, userHandler.Lockouts, time.Now())
+		assert.NoError(t, err)
+		assert.Zero(t, anonymized, "nothing is deleted before the grace period ends")
+
+		// Neither failed logins nor a suspension reason may tie the account to the person any longer
+		assert.NoError(t, lockout.Accounts(userHandler.Lockouts).Fail(context.Background(), lockout.AccountKey("Leaving@gmail.com"), time.Now()))
+		_, err = db.DB.Exec("UPDATE users SET suspension_reason = 'Harassed a neighbour' WHERE id = ?", owner.ID)
+		assert.NoError(t, err)
+
+		anonymized, err = models.AnonymizeDueAccounts(context.Background(), sqlUsers, userHandler.Lockouts, body.DeletionScheduledAt.Add(time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), anonymized)
+
+		user, err := sqlUsers.GetByID(context.Background(), owner.ID)
+		if assert.NoError(t, err) {
+			assert.NotEqual(t, "leaving@gmail.com", user.Email)
+			assert.NotEqual(t, "+250781475600", user.PhoneNumber)
+			assert.Empty(t, user.Address)
+			assert.Equal(t, "Deleted", user.FirstName)
+			assert.Empty(t, user.Roles)
+			assert.Empty(t, user.SuspensionReason)
+			assert.NotNil(t, user.DeletedAt)
+		}
+		attempts, err := userHandler.Lockouts.Get(context.Background(), lockout.Accounts(userHandler.Lockouts).Prefix+"leaving@gmail.com")
+		assert.NoError(t, err)
+		assert.Zero(t, attempts.Failures)
+
+		kept, err := sqlAnnouncements.GetByID(context.Background(), mine.ID)
+		if assert.NoError(t, err, "announcements are kept") {
+			assert.Equal(t, owner.ID, kept.OwnerID)
+		}
+
+		resp = testRequest(router, http.MethodGet, "/users/me/announcements", ownerToken, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		resp = testRequest(router, http.MethodPost, "/users/login", "", `{"email": "leaving@gmail.com", "password": "1234"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+	t.Run("The last admin cannot delete their account", func(t *testing.T) {
+		admin, adminToken := createTestUser(t, "leaving-admin@gmail.com", "+250781475602", models.RoleAdmin)
+		resp := testRequest(router, http.MethodDelete, "/users/me", adminToken, `{"password": "1234"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"code":"last_admin"`)
+
+		successor, successorToken := createTestUser(t, "successor-admin@gmail.com", "+250781475603", models.RoleAdmin)
+		resp = testRequest(router, http.MethodDelete, "/users/me", adminToken, `{"password": "1234"}`)
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+
+		// An admin whose deletion is scheduled is about to go too
+		resp = testRequest(router, http.MethodDelete, "/users/me", successorToken, `{"password": "1234"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"code":"last_admin"`)
+
+		// Should the successor lose the role during the grace period, the account is kept
+		assert.NoError(t, sqlUsers.RevokeRole(context.Background(), successor.ID, models.RoleAdmin))
+		anonymized, err := models.AnonymizeDueAccounts(context.Background(), sqlUsers, userHandler.Lockouts, time.Now().Add(365*24*time.Hour))
+		assert.NoError(t, err)
+		assert.Zero(t, anonymized)
+		kept, err := sqlUsers.GetByID(context.Background(), admin.ID)
+		if assert.NoError(t, err) {
+			assert.Nil(t, kept.DeletedAt)
+			assert.Equal(t, []models.Role{models.RoleAdmin, models.RoleAdvertiser}, kept.Roles)
+		}
+	})
+}
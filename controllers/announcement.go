@@ -1,13 +1,27 @@
 package controllers
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ngirimana/AnnounceIT/models"
 )
 
+// AnnouncementHandler serves the announcement endpoints
+type AnnouncementHandler struct {
+	Announcements models.AnnouncementStore
+	// Users is needed to check that advertisers have verified their email address
+	Users models.UserStore
+	Flags models.FlagStore
+	// Blacklist is needed to keep blacklisted users from creating and editing announcements
+	Blacklist models.BlacklistStore
+}
+
 // CreateAnnouncement godoc
 // @Summary Create an announcement
 // @Description Create an announcement and save it to the database
@@ -19,68 +33,457 @@ import (
 // @Success 201 {object} utils.AnnouncementSuccessResponse "Announcement created successfully"
 // @Failure 400 {object} utils.ErrorResponse "Could not parse request body"
 // @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.BlacklistedErrorResponse "User is blacklisted, has not verified their email address, or lacks the announcements:create permission"
+// @Failure 422 {object} utils.ValidationErrorResponse "Text is empty or longer than ANNOUNCEMENT_MAX_LENGTH, a date is missing, or end_date is not after start_date"
 // @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
 // @Router /announcements [post]
-func CreateAnnouncement(context *gin.Context) {
+func (h *AnnouncementHandler) CreateAnnouncement(context *gin.Context) {
 	var announcement models.Announcement
 	err := context.ShouldBindJSON(&announcement)
+	if rejectInvalidFields(context, err, &announcement) {
+		return
+	}
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"error": "could not parse request body"})
+		context.Error(models.BadRequest("invalid_request", "could not parse request body"))
+		return
+	}
+
+	if rejectUnverified(context, h.Users) || rejectBlacklisted(context, h.Blacklist) {
 		return
 	}
 
 	announcement.OwnerID = context.GetInt64("userId")
-	err = announcement.Create()
+	err = h.Announcements.Create(context.Request.Context(), &announcement)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		context.Error(err)
 		return
 	}
 
 	context.JSON(http.StatusCreated, gin.H{"message": "Announcement created successfully", "announcement": announcement})
 }
 
+// GetAnnouncements godoc
 // @Summary Get all announcements
-// @Description Retrieve all announcements
+// @Description Retrieve announcements matching the given filters, one page at a time. Pass the returned next_cursor to fetch the following page. Without a token only active announcements are listed; advertisers also see their own, and moderators, auditors and admins see everything.
 // @Tags Announcements
 // @Produce json
-// @Success 200 {object} []models.Announcement "Announcements retrieved successfully"
+// @Param Authorization header string false "Bearer token"
+// @Param X-API-Key header string false "API key, instead of a bearer token"
+// @Param status query string false "Only announcements with this status" Enums(pending, accepted, declined, active, deactivated)
+// @Param owner_id query int false "Only announcements of this owner"
+// @Param start_date_from query string false "Earliest start date (RFC 3339)"
+// @Param start_date_to query string false "Latest start date (RFC 3339)"
+// @Param end_date_from query string false "Earliest end date (RFC 3339)"
+// @Param end_date_to query string false "Latest end date (RFC 3339)"
+// @Param create_date_from query string false "Earliest creation date (RFC 3339)"
+// @Param create_date_to query string false "Latest creation date (RFC 3339)"
+// @Param sort query string false "Sort column" Enums(id, start_date, end_date, create_date) default(create_date)
+// @Param order query string false "Sort order" Enums(asc, desc) default(desc)
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} utils.AnnouncementListResponse "Announcements retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid query parameter"
 // @Failure 500 {object} utils.ErrorResponse "Could not fetch announcements"
 // @Router /announcements [get]
-func GetAnnouncements(context *gin.Context) {
-	announcements, err := models.GetAnnouncements()
+func (h *AnnouncementHandler) GetAnnouncements(context *gin.Context) {
+	filter, err := parseAnnouncementFilter(context)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch announcements"})
+		context.Error(err)
 		return
 	}
 
-	if len(announcements) == 0 {
-		context.JSON(http.StatusNotFound, gin.H{"error": "No announcements found"})
+	// Callers who cannot read everything only see active announcements, plus their own
+	if !callerCan(context, models.PermReadAllAnnouncements) {
+		filter.PublicOnly = true
+		filter.VisibleTo = context.GetInt64("userId")
+	}
+
+	listAnnouncements(context, h.Announcements, filter)
+}
+
+// GetMyAnnouncements godoc
+// @Summary Get my announcements
+// @Description Retrieve the announcements of the authenticated advertiser, in every status, one page at a time.
+// @Tags Announcements
+// @Produce json
+// @Param Authorization header string false "Bearer token"
+// @Param X-API-Key header string false "API key with the announcements:read scope, instead of a bearer token"
+// @Param status query string false "Only announcements with this status" Enums(pending, accepted, declined, active, deactivated)
+// @Param start_date_from query string false "Earliest start date (RFC 3339)"
+// @Param start_date_to query string false "Latest start date (RFC 3339)"
+// @Param end_date_from query string false "Earliest end date (RFC 3339)"
+// @Param end_date_to query string false "Latest end date (RFC 3339)"
+// @Param create_date_from query string false "Earliest creation date (RFC 3339)"
+// @Param create_date_to query string false "Latest creation date (RFC 3339)"
+// @Param sort query string false "Sort column" Enums(id, start_date, end_date, create_date) default(create_date)
+// @Param order query string false "Sort order" Enums(asc, desc) default(desc)
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} utils.AnnouncementListResponse "Announcements retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid query parameter"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch announcements"
+// @Router /users/me/announcements [get]
+func (h *AnnouncementHandler) GetMyAnnouncements(context *gin.Context) {
+	filter, err := parseAnnouncementFilter(context)
+	if err != nil {
+		context.Error(err)
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"announcements": announcements, "message": "Announcements retrieved successfully"})
+	ownerID := context.GetInt64("userId")
+	filter.OwnerID = &ownerID
+	listAnnouncements(context, h.Announcements, filter)
 }
 
 // @Summary Get a single announcement
-// @Description Retrieve an announcement by its ID
+// @Description Retrieve an announcement by its ID. Announcements that are not active are only visible to their owner and to users with the announcements:read_all permission.
 // @Tags Announcements
 // @Produce json
+// @Param Authorization header string false "Bearer token"
+// @Param X-API-Key header string false "API key, instead of a bearer token"
 // @Param id path int true "Announcement ID"
 // @Success 200 {object} utils.AnnouncementSuccessResponse "Announcement retrieved successfully"
 // @Failure 400 {object} utils.ErrorResponse "Invalid announcement ID"
 // @Failure 404 {object} utils.ErrorResponse "Announcement not found"
 // @Router /announcements/{id} [get]
-func GetAnnouncement(context *gin.Context) {
+func (h *AnnouncementHandler) GetAnnouncement(context *gin.Context) {
 	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement ID"})
+		context.Error(models.BadRequest("invalid_announcement_id", "Invalid announcement ID"))
 		return
 	}
-	announcement, err := models.GetAnnouncementByID(id)
-	if err != nil {
-		context.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+	announcement, err := h.Announcements.GetByID(context.Request.Context(), id)
+	if err != nil || !announcement.IsVisibleTo(context.GetInt64("userId"), callerCan(context, models.PermReadAllAnnouncements)) {
+		context.Error(models.NotFound("announcement_not_found", "Announcement not found"))
 		return
 	}
 
 	context.JSON(http.StatusOK, gin.H{"announcement": announcement, "message": "Announcement retrieved successfully"})
 }
+
+// UpdateAnnouncement godoc
+// @Summary Update an announcement
+// @Description Update the text and dates of an announcement. Only the owner may edit, and only while it is pending or declined. PUT replaces all fields, PATCH leaves unspecified fields alone.
+// @Tags Announcements
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Announcement ID"
+// @Param announcement body models.AnnouncementUpdate true "Fields to update"
+// @Success 200 {object} utils.AnnouncementSuccessResponse "Announcement updated successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid announcement ID or request body"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.BlacklistedErrorResponse "Only the owner can update this announcement, user is blacklisted, or lacks the announcements:create permission"
+// @Failure 404 {object} utils.ErrorResponse "Announcement not found"
+// @Failure 409 {object} utils.ErrorResponse "Announcement can no longer be edited"
+// @Failure 422 {object} utils.ValidationErrorResponse "Text is empty or too long, or end_date is not after start_date"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /announcements/{id} [put]
+// @Router /announcements/{id} [patch]
+func (h *AnnouncementHandler) UpdateAnnouncement(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_announcement_id", "Invalid announcement ID"))
+		return
+	}
+
+	var update models.AnnouncementUpdate
+	err = context.ShouldBindJSON(&update)
+	if rejectInvalidFields(context, err, &update) {
+		return
+	}
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse request body"))
+		return
+	}
+	if context.Request.Method == http.MethodPut && !update.IsComplete() {
+		context.Error(models.BadRequest("incomplete_replacement", "text, start_date and end_date are required"))
+		return
+	}
+
+	announcement, err := h.Announcements.GetByID(context.Request.Context(), id)
+	if err != nil {
+		context.Error(models.NotFound("announcement_not_found", "Announcement not found"))
+		return
+	}
+	if announcement.OwnerID != context.GetInt64("userId") {
+		context.Error(models.Forbidden("not_announcement_owner", "Only the owner can update this announcement"))
+		return
+	}
+	if !announcement.IsEditable() {
+		context.Error(models.Conflict("announcement_locked", "Announcement can no longer be edited"))
+		return
+	}
+	if rejectBlacklisted(context, h.Blacklist) {
+		return
+	}
+
+	announcement.Apply(update)
+	if err := announcement.CheckDates(); err != nil {
+		invalidField(context, models.FieldError{Field: "end_date", Code: "invalid_date_range", Message: "must be after start_date"})
+		return
+	}
+	err = h.Announcements.Update(context.Request.Context(), announcement)
+	if err != nil {
+		context.Error(err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Announcement updated successfully", "announcement": announcement})
+}
+
+// DeleteAnnouncement godoc
+// @Summary Delete an announcement
+// @Description Soft-delete an announcement. Deleted announcements are hidden from listings and can be restored until they are purged. Requires the announcements:delete permission.
+// @Tags Announcements
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Announcement ID"
+// @Success 200 {object} utils.MessageResponse "Announcement deleted successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid announcement ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "Announcement not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /announcements/{id} [delete]
+func (h *AnnouncementHandler) DeleteAnnouncement(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_announcement_id", "Invalid announcement ID"))
+		return
+	}
+
+	err = h.Announcements.Delete(context.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		context.Error(models.NotFound("announcement_not_found", "Announcement not found"))
+		return
+	}
+	if err != nil {
+		context.Error(err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Announcement deleted successfully"})
+}
+
+// RestoreAnnouncement godoc
+// @Summary Restore a deleted announcement
+// @Description Undo the soft delete of an announcement that has not been purged yet. Requires the announcements:delete permission.
+// @Tags Announcements
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Announcement ID"
+// @Success 200 {object} utils.AnnouncementSuccessResponse "Announcement restored successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid announcement ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "Deleted announcement not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /announcements/{id}/restore [post]
+func (h *AnnouncementHandler) RestoreAnnouncement(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_announcement_id", "Invalid announcement ID"))
+		return
+	}
+
+	err = h.Announcements.Restore(context.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		context.Error(models.NotFound("deleted_announcement_not_found", "Deleted announcement not found"))
+		return
+	}
+	if err != nil {
+		context.Error(err)
+		return
+	}
+
+	announcement, err := h.Announcements.GetByID(context.Request.Context(), id)
+	if err != nil {
+		context.Error(err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Announcement restored successfully", "announcement": announcement})
+}
+
+// GetDeletedAnnouncements godoc
+// @Summary Get deleted announcements
+// @Description Retrieve soft-deleted announcements that have not been purged yet. Requires the announcements:read_all permission.
+// @Tags Announcements
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} []models.Announcement "Deleted announcements retrieved successfully"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch deleted announcements"
+// @Router /announcements/deleted [get]
+func (h *AnnouncementHandler) GetDeletedAnnouncements(context *gin.Context) {
+	announcements, err := h.Announcements.ListDeleted(context.Request.Context())
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch deleted announcements: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"announcements": announcements, "message": "Deleted announcements retrieved successfully"})
+}
+
+// ChangeAnnouncementStatus godoc
+// @Summary Change the status of an announcement
+// @Description Move an announcement to another status. Only transitions allowed from the current status are accepted, and each one is recorded with the moderator and an optional reason. Requires the announcements:moderate permission.
+// @Tags Announcements
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Announcement ID"
+// @Param status body models.StatusChangeRequest true "New status and optional reason"
+// @Success 200 {object} utils.StatusChangeSuccessResponse "Announcement status changed successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid announcement ID, request body or status"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "Announcement not found"
+// @Failure 409 {object} utils.StatusTransitionErrorResponse "Transition not allowed from the current status"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /announcements/{id}/status [patch]
+func (h *AnnouncementHandler) ChangeAnnouncementStatus(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_announcement_id", "Invalid announcement ID"))
+		return
+	}
+
+	var request models.StatusChangeRequest
+	err = context.ShouldBindJSON(&request)
+	if errors.Is(err, models.ErrUnknownStatus) {
+		context.Error(models.BadRequest("unknown_status", err.Error()))
+		return
+	}
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse request body"))
+		return
+	}
+	next := *request.Status
+
+	announcement, err := h.Announcements.GetByID(context.Request.Context(), id)
+	if err != nil {
+		context.Error(models.NotFound("announcement_not_found", "Announcement not found"))
+		return
+	}
+	current := announcement.Status
+
+	change, err := announcement.ChangeStatus(context.Request.Context(), h.Announcements, next, context.GetInt64("userId"), request.Reason)
+	if errors.Is(err, models.ErrInvalidTransition) {
+		message := "Cannot change status from " + current.String() + " to " + next.String()
+		context.Error(models.Conflict("invalid_transition", message).With("allowed", current.AllowedTransitions()))
+		return
+	}
+	if err != nil {
+		context.Error(err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Announcement status changed successfully", "announcement": announcement, "change": change})
+}
+
+// GetAnnouncementStatusHistory godoc
+// @Summary Get the status history of an announcement
+// @Description Retrieve every recorded status transition of an announcement, oldest first. Requires the announcements:read_all permission.
+// @Tags Announcements
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Announcement ID"
+// @Success 200 {object} []models.StatusChange "Status history retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid announcement ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "Announcement not found"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch status history"
+// @Router /announcements/{id}/status/history [get]
+func (h *AnnouncementHandler) GetAnnouncementStatusHistory(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_announcement_id", "Invalid announcement ID"))
+		return
+	}
+
+	if _, err := h.Announcements.GetByID(context.Request.Context(), id); err != nil {
+		context.Error(models.NotFound("announcement_not_found", "Announcement not found"))
+		return
+	}
+
+	changes, err := h.Announcements.StatusChanges(context.Request.Context(), id)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch status history: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"changes": changes, "message": "Status history retrieved successfully"})
+}
+
+// listAnnouncements writes one page of announcements matching the filter
+func listAnnouncements(context *gin.Context, store models.AnnouncementStore, filter models.AnnouncementFilter) {
+	announcements, nextCursor, err := store.List(context.Request.Context(), filter)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch announcements: %w", err))
+		return
+	}
+
+	response := gin.H{"announcements": announcements, "next_cursor": nil, "message": "Announcements retrieved successfully"}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+// parseAnnouncementFilter reads the listing filters from the query string, failing with a bad request error
+func parseAnnouncementFilter(context *gin.Context) (models.AnnouncementFilter, error) {
+	filter := models.AnnouncementFilter{
+		Sort:   context.Query("sort"),
+		Order:  context.Query("order"),
+		Cursor: context.Query("cursor"),
+	}
+
+	if value := context.Query("status"); value != "" {
+		status, err := models.ParseStatus(value)
+		if err != nil {
+			return filter, models.BadRequest("invalid_query", err.Error())
+		}
+		filter.Statuses = []models.Status{status}
+	}
+	if value := context.Query("owner_id"); value != "" {
+		ownerID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, models.BadRequest("invalid_query", "invalid owner_id")
+		}
+		filter.OwnerID = &ownerID
+	}
+	if value := context.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, models.BadRequest("invalid_query", "invalid limit")
+		}
+		filter.Limit = limit
+	}
+
+	dates := map[string]**time.Time{
+		"start_date_from":  &filter.StartFrom,
+		"start_date_to":    &filter.StartTo,
+		"end_date_from":    &filter.EndFrom,
+		"end_date_to":      &filter.EndTo,
+		"create_date_from": &filter.CreatedFrom,
+		"create_date_to":   &filter.CreatedTo,
+	}
+	for name, target := range dates {
+		value := context.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, models.BadRequest("invalid_query", "invalid "+name+", expected an RFC 3339 date")
+		}
+		*target = &parsed
+	}
+
+	return filter, nil
+}
@@ -1 +1,629 @@
 package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestAnnouncement inserts an announcement for the given owner and forces its status
+func createTestAnnouncement(t *testing.T, ownerID int64, status models.Status) models.Announcement {
+	announcement := models.Announcement{
+		OwnerID:   ownerID,
+		Text:      "Announcement to update",
+		StartDate: time.Date(2030, 1, 1, 13, 30, 0, 0, time.UTC),
+		EndDate:   time.Date(2030, 1, 1, 15, 30, 0, 0, time.UTC),
+	}
+	err := sqlAnnouncements.Create(context.Background(), &announcement)
+	assert.NoError(t, err, "Failed to insert test announcement")
+
+	_, err = db.DB.Exec("UPDATE announcements SET status = ? WHERE id = ?", status, announcement.ID)
+	assert.NoError(t, err, "Failed to set test announcement status")
+	announcement.Status = status
+	return announcement
+}
+
+// testUser returns the advertiser used by tests that need an authenticated caller, creating it
+// if an earlier test truncated the users table, together with a valid token for it
+func testUser(t *testing.T) (models.User, string) {
+	user, err := sqlUsers.GetByEmail(context.Background(), "testuser@gmail.com")
+	if err != nil {
+		created, _ := createTestUser(t, "testuser@gmail.com", "+250781475199")
+		user = &created
+	}
+
+	return *user, sessionToken(t, user)
+}
+
+// createTestUser saves an advertiser, grants it any extra roles, and returns it with a token for its ID
+func createTestUser(t *testing.T, email, phoneNumber string, roles ...models.Role) (models.User, string) {
+	user := models.User{
+		Email:       email,
+		Password:    "1234",
+		FirstName:   "Test",
+		LastName:    "User",
+		PhoneNumber: phoneNumber,
+		Address:     "KG 23 ST",
+	}
+	err := user.Save(context.Background(), sqlUsers)
+	assert.NoError(t, err, "Failed to insert test user")
+	assert.NoError(t, sqlUsers.VerifyEmail(context.Background(), user.ID, user.Email), "Failed to verify test user")
+	for _, role := range roles {
+		assert.NoError(t, sqlUsers.GrantRole(context.Background(), user.ID, role, models.SystemUserID), "Failed to grant test role")
+	}
+	user.Roles, err = sqlUsers.Roles(context.Background(), user.ID)
+	assert.NoError(t, err, "Failed to load test roles")
+	return user, sessionToken(t, &user)
+}
+
+// sessionToken starts a session for the user, as logging in does, and returns an access token of the session
+func sessionToken(t *testing.T, user *models.User) string {
+	sessionId, _, err := userHandler.Sessions.Start(context.Background(), user.ID, user.TokenVersion, "192.0.2.10", "", time.Hour)
+	assert.NoError(t, err, "Failed to start test session")
+	token, err := helpers.GenerateToken(user.Email, user.ID, user.TokenVersion, sessionId, user.RoleNames())
+	assert.NoError(t, err, "Failed to generate test token")
+	return token
+}
+
+// newTestRouter returns a router that answers errors as problems, like the application's
+func newTestRouter() *gin.Engine {
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	return router
+}
+
+// testRequest serves a JSON request on router, authorized with token unless it is empty
+func testRequest(router http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	return sendTestRequest(router, method, path, body, map[string]string{"Authorization": token})
+}
+
+// sendTestRequest serves a JSON request with the given headers on router, leaving out those that are empty.
+// Every request comes from the same client address so that sessions and lockouts can be checked against it.
+func sendTestRequest(router http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	req.RemoteAddr = "192.0.2.10:52000"
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestUpdateAnnouncement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Disable Gin's default writer to prevent log output
+	gin.DefaultWriter = io.Discard
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.PUT("/announcements/:id", middlewares.Authenticate, announcementHandler.UpdateAnnouncement)
+	router.PATCH("/announcements/:id", middlewares.Authenticate, announcementHandler.UpdateAnnouncement)
+
+	owner, testToken := testUser(t)
+	stranger := owner.ID + 1
+
+	owned := createTestAnnouncement(t, owner.ID, models.Pending)
+	declined := createTestAnnouncement(t, owner.ID, models.Declined)
+	active := createTestAnnouncement(t, owner.ID, models.Active)
+	someoneElses := createTestAnnouncement(t, stranger, models.Pending)
+
+	idOf := func(a models.Announcement) string { return strconv.FormatInt(a.ID, 10) }
+
+	tests := []struct {
+		name           string
+		method         string
+		id             string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Invalid ID Format",
+			method:         http.MethodPatch,
+			id:             "abc",
+			body:           `{"text": "Updated"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid announcement ID",
+		},
+		{
+			name:           "Announcement Not Found",
+			method:         http.MethodPatch,
+			id:             "999999",
+			body:           `{"text": "Updated"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Announcement not found",
+		},
+		{
+			name:           "Not the owner",
+			method:         http.MethodPatch,
+			id:             idOf(someoneElses),
+			body:           `{"text": "Updated"}`,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "Only the owner can update this announcement",
+		},
+		{
+			name:           "Locked once active",
+			method:         http.MethodPatch,
+			id:             idOf(active),
+			body:           `{"text": "Updated"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   "Announcement can no longer be edited",
+		},
+		{
+			name:           "PUT requires every field",
+			method:         http.MethodPut,
+			id:             idOf(owned),
+			body:           `{"text": "Updated"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "text, start_date and end_date are required",
+		},
+		{
+			name:           "PATCH text only",
+			method:         http.MethodPatch,
+			id:             idOf(owned),
+			body:           `{"text": "Updated text"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Announcement updated successfully",
+		},
+		{
+			name:           "PATCH blank text",
+			method:         http.MethodPatch,
+			id:             idOf(owned),
+			body:           `{"text": "   "}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"field":"text","code":"required"`,
+		},
+		{
+			name:           "PATCH end before start",
+			method:         http.MethodPatch,
+			id:             idOf(owned),
+			body:           `{"end_date": "2000-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"field":"end_date","code":"invalid_date_range"`,
+		},
+		{
+			name:   "PUT declined announcement",
+			method: http.MethodPut,
+			id:     idOf(declined),
+			body: `{
+				"text": "Replaced text",
+				"start_date": "2030-02-01T13:30:00Z",
+				"end_date": "2030-02-01T15:30:00Z"
+			}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Announcement updated successfully",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "/announcements/"+tt.id, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", testToken)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.expectedBody)
+		})
+	}
+
+	// PATCH must leave the dates it was not given untouched
+	updated, err := sqlAnnouncements.GetByID(context.Background(), owned.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Updated text", updated.Text)
+	assert.True(t, owned.StartDate.Equal(updated.StartDate))
+	assert.True(t, owned.EndDate.Equal(updated.EndDate))
+
+	replaced, err := sqlAnnouncements.GetByID(context.Background(), declined.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Replaced text", replaced.Text)
+}
+
+func TestDeleteAndRestoreAnnouncement(t *testing.T) {
+	t.Parallel()
+	handler, announcements := memoryAnnouncementHandler()
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.GET("/announcements/:id", fakeAuthenticate, handler.GetAnnouncement)
+	router.GET("/announcements/deleted", fakeAuthenticate, middlewares.RequirePermission(models.PermReadAllAnnouncements), handler.GetDeletedAnnouncements)
+	router.DELETE("/announcements/:id", fakeAuthenticate, middlewares.RequirePermission(models.PermDeleteAnnouncements), handler.DeleteAnnouncement)
+	router.POST("/announcements/:id/restore", fakeAuthenticate, middlewares.RequirePermission(models.PermDeleteAnnouncements), handler.RestoreAnnouncement)
+
+	adminToken := fakeToken(1, models.RoleAdmin)
+	advertiserToken := fakeToken(2, models.RoleAdvertiser)
+	announcement := createMemoryAnnouncement(t, announcements, 2, models.Pending)
+	id := strconv.FormatInt(announcement.ID, 10)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Advertiser cannot delete",
+			method:         http.MethodDelete,
+			path:           "/announcements/" + id,
+			token:          advertiserToken,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "Insufficient permissions",
+		},
+		{
+			name:           "Admin deletes",
+			method:         http.MethodDelete,
+			path:           "/announcements/" + id,
+			token:          adminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Announcement deleted successfully",
+		},
+		{
+			name:           "Deleted announcement is hidden",
+			method:         http.MethodGet,
+			path:           "/announcements/" + id,
+			token:          adminToken,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Announcement not found",
+		},
+		{
+			name:           "Deleting twice",
+			method:         http.MethodDelete,
+			path:           "/announcements/" + id,
+			token:          adminToken,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Announcement not found",
+		},
+		{
+			name:           "Admin lists deleted",
+			method:         http.MethodGet,
+			path:           "/announcements/deleted",
+			token:          adminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"id":` + id,
+		},
+		{
+			name:           "Admin restores",
+			method:         http.MethodPost,
+			path:           "/announcements/" + id + "/restore",
+			token:          adminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Announcement restored successfully",
+		},
+		{
+			name:           "Restored announcement is visible",
+			method:         http.MethodGet,
+			path:           "/announcements/" + id,
+			token:          adminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Announcement retrieved successfully",
+		},
+		{
+			name:           "Restoring a live announcement",
+			method:         http.MethodPost,
+			path:           "/announcements/" + id + "/restore",
+			token:          adminToken,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Deleted announcement not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.expectedBody)
+		})
+	}
+
+	// Purging only removes rows deleted before the cutoff
+	assert.NoError(t, announcements.Delete(context.Background(), announcement.ID))
+	purged, err := announcements.PurgeDeleted(context.Background(), time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+	purged, err = announcements.PurgeDeleted(context.Background(), time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
+
+func TestChangeAnnouncementStatus(t *testing.T) {
+	t.Parallel()
+	handler, announcements := memoryAnnouncementHandler()
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.PATCH("/announcements/:id/status", fakeAuthenticate, middlewares.RequirePermission(models.PermModerateAnnouncements), handler.ChangeAnnouncementStatus)
+	router.GET("/announcements/:id/status/history", fakeAuthenticate, middlewares.RequirePermission(models.PermReadAllAnnouncements), handler.GetAnnouncementStatusHistory)
+
+	adminID := int64(1)
+	adminToken := fakeToken(adminID, models.RoleAdmin)
+	announcement := createMemoryAnnouncement(t, announcements, 2, models.Pending)
+	id := strconv.FormatInt(announcement.ID, 10)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Unknown status",
+			body:           `{"status": "published"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `unknown status \"published\"`,
+		},
+		{
+			name:           "Numeric status",
+			body:           `{"status": 3}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "unknown status 3",
+		},
+		{
+			name:           "Missing status",
+			body:           `{"reason": "No status"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "could not parse request body",
+		},
+		{
+			name:           "Skipping review is rejected",
+			body:           `{"status": "active"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `"allowed":["accepted","declined"]`,
+		},
+		{
+			name:           "Pending to Accepted",
+			body:           `{"status": "Accepted", "reason": "Looks good"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"status":"accepted"`,
+		},
+		{
+			name:           "Accepted to Active",
+			body:           `{"status": "active"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Announcement status changed successfully",
+		},
+		{
+			name:           "Active cannot go back to Pending",
+			body:           `{"status": "pending"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `"allowed":["deactivated"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPatch, "/announcements/"+id+"/status", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", adminToken)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.expectedBody)
+		})
+	}
+
+	// Both successful transitions are recorded with the admin and reason
+	changes, err := announcements.StatusChanges(context.Background(), announcement.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, models.Pending, changes[0].FromStatus)
+		assert.Equal(t, models.Accepted, changes[0].ToStatus)
+		assert.Equal(t, adminID, changes[0].ChangedBy)
+		assert.Equal(t, "Looks good", changes[0].Reason)
+		assert.Equal(t, models.Active, changes[1].ToStatus)
+	}
+
+	resp := testRequest(router, http.MethodGet, "/announcements/"+id+"/status/history", adminToken, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"reason":"Looks good"`)
+
+	resp = testRequest(router, http.MethodGet, "/announcements/999999/status/history", adminToken, "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"code":"announcement_not_found"`)
+}
+
+func TestGetAnnouncementsFiltering(t *testing.T) {
+	t.Parallel()
+	handler, announcements := memoryAnnouncementHandler()
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.GET("/announcements", fakeAuthenticate, handler.GetAnnouncements)
+
+	// Admins see announcements in every status
+	adminToken := fakeToken(3, models.RoleAdmin)
+
+	first := createMemoryAnnouncement(t, announcements, 1, models.Active)
+	second := createMemoryAnnouncement(t, announcements, 1, models.Pending)
+	third := createMemoryAnnouncement(t, announcements, 2, models.Active)
+	fourth := createMemoryAnnouncement(t, announcements, 2, models.Declined)
+	fifth := createMemoryAnnouncement(t, announcements, 2, models.Active)
+
+	// list performs the request and returns the announcement IDs and next cursor
+	list := func(t *testing.T, query string) (int, []int64, interface{}) {
+		req, _ := http.NewRequest(http.MethodGet, "/announcements?"+query, nil)
+		req.Header.Set("Authorization", adminToken)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		var body struct {
+			Announcements []models.Announcement `json:"announcements"`
+			NextCursor    interface{}           `json:"next_cursor"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		ids := []int64{}
+		for _, a := range body.Announcements {
+			ids = append(ids, a.ID)
+		}
+		return resp.Code, ids, body.NextCursor
+	}
+
+	t.Run("Filter by status", func(t *testing.T) {
+		code, ids, _ := list(t, "status=active&sort=id&order=asc")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []int64{first.ID, third.ID, fifth.ID}, ids)
+	})
+
+	t.Run("Filter by owner", func(t *testing.T) {
+		code, ids, _ := list(t, "owner_id=1&sort=id&order=asc")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []int64{first.ID, second.ID}, ids)
+	})
+
+	t.Run("Nothing matches", func(t *testing.T) {
+		code, ids, next := list(t, "start_date_from=2031-01-01T00:00:00Z")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, ids)
+		assert.Nil(t, next)
+	})
+
+	t.Run("Paginate with cursor", func(t *testing.T) {
+		seen := []int64{}
+		query := "sort=id&order=desc&limit=2"
+		for pages := 0; pages < 5; pages++ {
+			code, ids, next := list(t, query)
+			assert.Equal(t, http.StatusOK, code)
+			seen = append(seen, ids...)
+			if next == nil {
+				break
+			}
+			query = "sort=id&order=desc&limit=2&cursor=" + next.(string)
+		}
+		assert.Equal(t, []int64{fifth.ID, fourth.ID, third.ID, second.ID, first.ID}, seen)
+	})
+
+	t.Run("Paginate by date", func(t *testing.T) {
+		code, ids, next := list(t, "sort=create_date&order=asc&limit=3")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, ids, 3)
+		if assert.NotNil(t, next) {
+			code, rest, next := list(t, "sort=create_date&order=asc&limit=3&cursor="+next.(string))
+			assert.Equal(t, http.StatusOK, code)
+			assert.Len(t, rest, 2)
+			assert.Nil(t, next)
+			assert.NotContains(t, rest, ids[2])
+		}
+	})
+
+	badQueries := map[string]string{
+		"Unknown status":       "status=published",
+		"Invalid owner":        "owner_id=abc",
+		"Invalid date":         "end_date_to=tomorrow",
+		"Unsupported sort":     "sort=text",
+		"Unsupported order":    "order=sideways",
+		"Cursor of other sort": "sort=id&cursor=" + "eyJzIjoiY3JlYXRlX2RhdGUiLCJvIjoiZGVzYyIsImkiOjF9",
+		"Garbled cursor":       "cursor=not-a-cursor",
+	}
+	for name, query := range badQueries {
+		t.Run(name, func(t *testing.T) {
+			code, _, _ := list(t, query)
+			assert.Equal(t, http.StatusBadRequest, code)
+		})
+	}
+}
+
+func TestAnnouncementVisibility(t *testing.T) {
+	t.Parallel()
+	handler, announcements := memoryAnnouncementHandler()
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.GET("/announcements", fakeAuthenticate, handler.GetAnnouncements)
+	router.GET("/announcements/:id", fakeAuthenticate, handler.GetAnnouncement)
+	router.GET("/users/me/announcements", fakeAuthenticate, handler.GetMyAnnouncements)
+
+	owner, stranger := int64(1), int64(2)
+	testToken := fakeToken(owner, models.RoleAdvertiser)
+
+	mineActive := createMemoryAnnouncement(t, announcements, owner, models.Active)
+	minePending := createMemoryAnnouncement(t, announcements, owner, models.Pending)
+	theirsActive := createMemoryAnnouncement(t, announcements, stranger, models.Active)
+	theirsDeclined := createMemoryAnnouncement(t, announcements, stranger, models.Declined)
+
+	ids := func(t *testing.T, path, token string) []int64 {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var body struct {
+			Announcements []models.Announcement `json:"announcements"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		result := []int64{}
+		for _, a := range body.Announcements {
+			result = append(result, a.ID)
+		}
+		return result
+	}
+
+	t.Run("Public listing only shows active", func(t *testing.T) {
+		assert.Equal(t, []int64{mineActive.ID, theirsActive.ID}, ids(t, "/announcements?sort=id&order=asc", ""))
+	})
+
+	t.Run("Advertiser also sees their own", func(t *testing.T) {
+		assert.Equal(t, []int64{mineActive.ID, minePending.ID, theirsActive.ID}, ids(t, "/announcements?sort=id&order=asc", testToken))
+	})
+
+	t.Run("My announcements", func(t *testing.T) {
+		assert.Equal(t, []int64{mineActive.ID, minePending.ID}, ids(t, "/users/me/announcements?sort=id&order=asc", testToken))
+	})
+
+	t.Run("My announcements by status", func(t *testing.T) {
+		assert.Equal(t, []int64{minePending.ID}, ids(t, "/users/me/announcements?status=pending", testToken))
+	})
+
+	t.Run("My announcements ignore owner_id", func(t *testing.T) {
+		assert.Equal(t, []int64{mineActive.ID, minePending.ID}, ids(t, "/users/me/announcements?owner_id="+strconv.FormatInt(stranger, 10)+"&sort=id&order=asc", testToken))
+	})
+
+	singles := []struct {
+		name           string
+		id             int64
+		token          string
+		expectedStatus int
+	}{
+		{"Anonymous reads active", theirsActive.ID, "", http.StatusOK},
+		{"Anonymous cannot read pending", minePending.ID, "", http.StatusNotFound},
+		{"Owner reads pending", minePending.ID, testToken, http.StatusOK},
+		{"Advertiser cannot read someone else's declined", theirsDeclined.ID, testToken, http.StatusNotFound},
+	}
+	for _, tt := range singles {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/announcements/"+strconv.FormatInt(tt.id, 10), nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a named key for an integration, limited to the given scopes, which must be permissions of your roles. Send it in the X-API-Key header. The key is only returned by this request; store it safely.
+// @Tags API keys
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.APIKeyRequest true "Name, scopes and optional expiry"
+// @Success 201 {object} utils.APIKeySuccessResponse "API key created successfully"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request, unknown scope, or expiry in the past"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Scope not granted by your roles, or the request was made with an API key"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/api-keys [post]
+func (h *UserHandler) CreateAPIKey(context *gin.Context) {
+	var request models.APIKeyRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !scope.IsValid() {
+			context.Error(models.BadRequest("unknown_scope", "Unknown scope").With("scope", scope).With("allowed", models.Permissions))
+			return
+		}
+		if !callerCan(context, scope) {
+			context.Error(models.Forbidden("scope_not_granted", "Scope not granted by your roles").With("scope", scope))
+			return
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		context.Error(models.BadRequest("invalid_expiry", "expires_at must be in the future"))
+		return
+	}
+
+	key, fullKey, err := models.CreateAPIKey(context.Request.Context(), h.APIKeys, context.GetInt64("userId"), request.Name, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		context.Error(fmt.Errorf("could not create the API key: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{"message": "API key created successfully", "key": fullKey, "api_key": key})
+}
+
+// GetAPIKeys godoc
+// @Summary Get my API keys
+// @Description List the API keys of the authenticated user that have not been revoked, newest first. The keys themselves are not returned.
+// @Tags API keys
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} []models.APIKey "API keys retrieved successfully"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "The request was made with an API key"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch API keys"
+// @Router /users/me/api-keys [get]
+func (h *UserHandler) GetAPIKeys(context *gin.Context) {
+	keys, err := h.APIKeys.List(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch API keys: %w", err))
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "API keys retrieved successfully", "api_keys": keys})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Stop one of your API keys from working.
+// @Tags API keys
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "API key ID"
+// @Success 200 {object} utils.MessageResponse "API key revoked successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid API key ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "The request was made with an API key"
+// @Failure 404 {object} utils.ErrorResponse "API key not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/api-keys/{id} [delete]
+func (h *UserHandler) RevokeAPIKey(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_api_key_id", "Invalid API key ID"))
+		return
+	}
+
+	err = h.APIKeys.Revoke(context.Request.Context(), context.GetInt64("userId"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		context.Error(models.NotFound("api_key_not_found", "API key not found"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not revoke the API key: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
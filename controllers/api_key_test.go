@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	router := newTestRouter()
+	account := router.Group("/", middlewares.Authenticate, middlewares.RejectAPIKeys)
+	account.POST("/users/me/api-keys", userHandler.CreateAPIKey)
+	account.GET("/users/me/api-keys", userHandler.GetAPIKeys)
+	account.DELETE("/users/me/api-keys/:id", userHandler.RevokeAPIKey)
+	router.GET("/users/me/announcements", middlewares.Authenticate, middlewares.RequirePermission(models.PermReadAnnouncements), announcementHandler.GetMyAnnouncements)
+	router.POST("/announcements", middlewares.Authenticate, middlewares.RequirePermission(models.PermCreateAnnouncements), announcementHandler.CreateAnnouncement)
+	router.GET("/announcements", middlewares.OptionalAuthenticate, announcementHandler.GetAnnouncements)
+
+	db.TruncateUsersTable()
+	owner, token := createTestUser(t, "broadcaster@gmail.com", "+250781475107")
+	createTestAnnouncement(t, owner.ID, models.Active)
+
+	withToken := map[string]string{"Authorization": "Bearer " + token}
+
+	t.Run("Unknown scope", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodPost, "/users/me/api-keys", `{"name": "Playout", "scopes": ["everything"]}`, withToken)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Scopes are limited to the owner's roles", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodPost, "/users/me/api-keys", `{"name": "Playout", "scopes": ["announcements:moderate"]}`, withToken)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	var key string
+	var keyID int64
+	t.Run("Create a read-only key", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodPost, "/users/me/api-keys", `{"name": "Playout", "scopes": ["announcements:read"]}`, withToken)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		var body struct {
+			Key    string        `json:"key"`
+			APIKey models.APIKey `json:"api_key"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.True(t, strings.HasPrefix(body.Key, "aik_"+body.APIKey.Prefix+"_"))
+		key, keyID = body.Key, body.APIKey.ID
+	})
+
+	t.Run("Key reads announcements", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", map[string]string{"X-API-Key": key})
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodGet, "/announcements", "", map[string]string{"X-API-Key": key})
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Key cannot go beyond its scopes", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodPost, "/announcements", `{
+			"end_date": "2030-01-01T15:30:00.000Z",
+			"start_date": "2030-01-01T13:30:00.000Z",
+			"text": "Created with a key"
+		}`, map[string]string{"X-API-Key": key})
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Key cannot manage keys", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodPost, "/users/me/api-keys", `{"name": "Escalate", "scopes": ["announcements:create"]}`, map[string]string{"X-API-Key": key})
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Wrong key", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", map[string]string{"X-API-Key": key + "x"})
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Invalid API key")
+	})
+
+	t.Run("Listing records last use without the key", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, "/users/me/api-keys", "", withToken)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NotContains(t, resp.Body.String(), key)
+
+		var body struct {
+			APIKeys []models.APIKey `json:"api_keys"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		if assert.Len(t, body.APIKeys, 1) {
+			assert.NotNil(t, body.APIKeys[0].LastUsedAt)
+			assert.Equal(t, []models.Permission{models.PermReadAnnouncements}, body.APIKeys[0].Scopes)
+		}
+	})
+
+	t.Run("Expired key", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Minute)
+		_, expired, err := models.CreateAPIKey(context.Background(), userHandler.APIKeys, owner.ID, "Expired", []models.Permission{models.PermReadAnnouncements}, &expiresAt)
+		assert.NoError(t, err)
+		_, err = db.DB.Exec("UPDATE api_keys SET expires_at = ? WHERE name = 'Expired'", time.Now().Add(-time.Minute).UTC())
+		assert.NoError(t, err)
+
+		resp := sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", map[string]string{"X-API-Key": expired})
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Revoked key", func(t *testing.T) {
+		path := "/users/me/api-keys/" + strconv.FormatInt(keyID, 10)
+		resp := sendTestRequest(router, http.MethodDelete, path, "", withToken)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodDelete, path, "", withToken)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", map[string]string{"X-API-Key": key})
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticateTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Disable Gin's default writer to prevent log output
+	gin.DefaultWriter = io.Discard
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.GET("/users/me/announcements", middlewares.Authenticate, announcementHandler.GetMyAnnouncements)
+
+	// Registered first so that it runs after the environment is restored
+	t.Cleanup(func() { assert.NoError(t, helpers.CheckSigningKeys()) })
+	t.Setenv("JWT_SIGNING_KEYS", "old:old-secret-old-secret-old-secret, new:new-secret-new-secret-new-secret")
+	t.Setenv("JWT_SIGNING_KEY_ID", "new")
+	t.Setenv("ACCESS_TOKEN_TTL_MINUTES", "5")
+	assert.NoError(t, helpers.CheckSigningKeys())
+	db.TruncateUsersTable()
+	user, token := createTestUser(t, "auth@gmail.com", "+250781475104")
+	issued, err := helpers.VerifyToken(token)
+	assert.NoError(t, err)
+
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"jti":    "test-token",
+			"userId": user.ID,
+			"ver":    user.TokenVersion,
+			"sid":    issued.SessionID,
+			"iss":    "announceit",
+			"aud":    "announceit-api",
+			"exp":    time.Now().Add(time.Minute).Unix(),
+		}
+	}
+	sign := func(kid, secret string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString([]byte(secret))
+		assert.NoError(t, err)
+		return signed
+	}
+	without := func(claim string) jwt.MapClaims {
+		c := claims()
+		delete(c, claim)
+		return c
+	}
+	with := func(claim string, value interface{}) jwt.MapClaims {
+		c := claims()
+		c[claim] = value
+		return c
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{"Issued token", token, http.StatusOK},
+		{"Token signed with a retired key", sign("old", "old-secret-old-secret-old-secret", claims()), http.StatusOK},
+		{"Unknown key ID", sign("other", "new-secret-new-secret-new-secret", claims()), http.StatusUnauthorized},
+		{"Wrong secret", sign("new", "not-the-secret", claims()), http.StatusUnauthorized},
+		{"Missing userId", sign("new", "new-secret-new-secret-new-secret", without("userId")), http.StatusUnauthorized},
+		{"Missing jti", sign("new", "new-secret-new-secret-new-secret", without("jti")), http.StatusUnauthorized},
+		{"Missing ver", sign("new", "new-secret-new-secret-new-secret", without("ver")), http.StatusUnauthorized},
+		{"Missing sid", sign("new", "new-secret-new-secret-new-secret", without("sid")), http.StatusUnauthorized},
+		{"Non-numeric userId", sign("new", "new-secret-new-secret-new-secret", with("userId", "1")), http.StatusUnauthorized},
+		{"Wrong issuer", sign("new", "new-secret-new-secret-new-secret", with("iss", "someone-else")), http.StatusUnauthorized},
+		{"Wrong audience", sign("new", "new-secret-new-secret-new-secret", with("aud", "another-api")), http.StatusUnauthorized},
+		{"Expired", sign("new", "new-secret-new-secret-new-secret", with("exp", time.Now().Add(-time.Minute).Unix())), http.StatusUnauthorized},
+		{"No expiry", sign("new", "new-secret-new-secret-new-secret", without("exp")), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/users/me/announcements", nil)
+			req.Header.Set("Authorization", tt.token)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+		})
+	}
+
+	t.Run("Access tokens are short-lived", func(t *testing.T) {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, "new", parsed.Header["kid"])
+		expiresAt, err := parsed.Claims.GetExpirationTime()
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt.Time, 5*time.Second)
+	})
+
+	t.Run("Misconfigured key ID", func(t *testing.T) {
+		t.Setenv("JWT_SIGNING_KEY_ID", "missing")
+		assert.Error(t, helpers.CheckSigningKeys())
+	})
+}
+
+func TestAuthenticateSchemes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Disable Gin's default writer to prevent log output
+	gin.DefaultWriter = io.Discard
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.GET("/users/me/announcements", middlewares.Authenticate, announcementHandler.GetMyAnnouncements)
+	router.POST("/users/logout", middlewares.Authenticate, userHandler.Logout)
+
+	previous := middlewares.Authenticators
+	middlewares.Authenticators = append([]middlewares.Authenticator{}, previous...)
+	middlewares.Authenticators = append(middlewares.Authenticators, middlewares.BasicAuthenticator{Users: sqlUsers, TwoFactor: userHandler.TwoFactor, Lockouts: userHandler.Lockouts})
+	defer func() { middlewares.Authenticators = previous }()
+
+	db.TruncateUsersTable()
+	_, token := createTestUser(t, "schemes@gmail.com", "+250781475106")
+	basic := base64.StdEncoding.EncodeToString([]byte("schemes@gmail.com:1234"))
+	wrongPassword := base64.StdEncoding.EncodeToString([]byte("schemes@gmail.com:wrong"))
+
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+		expectedError  string
+		challenge      string
+	}{
+		{"Bearer scheme", "Bearer " + token, http.StatusOK, "", ""},
+		{"Scheme is case-insensitive", "bearer " + token, http.StatusOK, "", ""},
+		{"Bare token", token, http.StatusOK, "", ""},
+		{"Basic credentials", "Basic " + basic, http.StatusOK, "", ""},
+		{"Missing header", "", http.StatusUnauthorized, "Authorization token is required", `Bearer realm="announceit"`},
+		{"Invalid bearer token", "Bearer not-a-jwt", http.StatusUnauthorized, "Invalid token", `Bearer realm="announceit", error="invalid_token"`},
+		{"Wrong password", "Basic " + wrongPassword, http.StatusUnauthorized, "Invalid credentials", `Basic realm="announceit"`},
+		{"Unsupported scheme", "Digest username=someone", http.StatusUnauthorized, "Unsupported authorization scheme", `Bearer realm="announceit"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/users/me/announcements", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.expectedError)
+			if tt.challenge != "" {
+				assert.Contains(t, resp.Header().Values("WWW-Authenticate"), tt.challenge)
+			}
+		})
+	}
+
+	t.Run("Logout without a JWT", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/users/logout", nil)
+		req.Header.Set("Authorization", "Basic "+basic)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Wrong Basic passwords lock the account out", func(t *testing.T) {
+		t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "3")
+		assert.NoError(t, lockout.Accounts(userHandler.Lockouts).Reset(context.Background(), "schemes@gmail.com"))
+		for i := 0; i < 3; i++ {
+			resp := testRequest(router, http.MethodGet, "/users/me/announcements", "Basic "+wrongPassword, "")
+			assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		}
+
+		// Even the right password is refused until the lockout ends
+		resp := testRequest(router, http.MethodGet, "/users/me/announcements", "Basic "+basic, "")
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"code":"too_many_attempts"`)
+		retryAfter, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+		assert.NoError(t, err)
+		assert.InDelta(t, 60, retryAfter, 1)
+		assert.NoError(t, lockout.Accounts(userHandler.Lockouts).Reset(context.Background(), "schemes@gmail.com"))
+	})
+}
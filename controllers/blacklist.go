@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// BlacklistUser godoc
+// @Summary Blacklist a user
+// @Description Stop a user from creating or editing announcements, until expires_at or indefinitely. Requires the blacklist:manage permission.
+// @Tags Blacklist
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param request body models.BlacklistRequest true "Reason and optional expiry"
+// @Success 201 {object} utils.BlacklistSuccessResponse "User blacklisted successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID, request body or expiry"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/{id}/blacklist [post]
+func (h *UserHandler) BlacklistUser(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	var request models.BlacklistRequest
+	err = context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		context.Error(models.BadRequest("invalid_expiry", "expires_at must be in the future"))
+		return
+	}
+
+	_, err = h.Users.GetByID(context.Request.Context(), id)
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	change, err := models.BlacklistUser(context.Request.Context(), h.Blacklist, id, context.GetInt64("userId"), request.Reason, request.ExpiresAt)
+	if err != nil {
+		context.Error(fmt.Errorf("could not blacklist the user: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{"message": "User blacklisted successfully", "change": change})
+}
+
+// UnblacklistUser godoc
+// @Summary Remove a user from the blacklist
+// @Description Allow a blacklisted user to create and edit announcements again. Requires the blacklist:manage permission.
+// @Tags Blacklist
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param request body models.UnblacklistRequest false "Optional reason"
+// @Success 200 {object} utils.BlacklistSuccessResponse "User removed from the blacklist successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID or request body"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "User is not blacklisted"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/{id}/blacklist [delete]
+func (h *UserHandler) UnblacklistUser(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	// The body is optional for DELETE
+	var request models.UnblacklistRequest
+	if context.Request.ContentLength > 0 {
+		if err = context.ShouldBindJSON(&request); err != nil {
+			context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+			return
+		}
+	}
+
+	change, err := models.UnblacklistUser(context.Request.Context(), h.Blacklist, id, context.GetInt64("userId"), request.Reason)
+	if err != nil {
+		context.Error(fmt.Errorf("could not remove the user from the blacklist: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "User removed from the blacklist successfully", "change": change})
+}
+
+// GetBlacklistChanges godoc
+// @Summary Get blacklist changes
+// @Description List who was put on or taken off the blacklist, by whom and when, newest first. Requires the blacklist:read permission.
+// @Tags Blacklist
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param user_id query int false "Only changes for this user"
+// @Success 200 {object} []models.BlacklistChange "Blacklist changes retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user_id"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch blacklist changes"
+// @Router /blacklist [get]
+func (h *UserHandler) GetBlacklistChanges(context *gin.Context) {
+	var userId int64
+	if value := context.Query("user_id"); value != "" {
+		var err error
+		userId, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			context.Error(models.BadRequest("invalid_query", "invalid user_id"))
+			return
+		}
+	}
+
+	changes, err := h.Blacklist.Changes(context.Request.Context(), userId)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch blacklist changes: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"changes": changes, "message": "Blacklist changes retrieved successfully"})
+}
+
+// rejectBlacklisted fails the request with the reason when the caller is blacklisted, and reports whether it did
+func rejectBlacklisted(context *gin.Context, blacklist models.BlacklistStore) bool {
+	blacklisting, err := models.GetActiveBlacklisting(context.Request.Context(), blacklist, context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not check the blacklist: %w", err))
+		return true
+	}
+	if blacklisting == nil {
+		return false
+	}
+
+	rejection := models.Forbidden("blacklisted", "You are blacklisted from creating announcements").With("reason", blacklisting.Reason)
+	if blacklisting.ExpiresAt != nil {
+		rejection = rejection.With("expires_at", blacklisting.ExpiresAt)
+	}
+	context.Error(rejection)
+	return true
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlacklist(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/announcements", middlewares.Authenticate, announcementHandler.CreateAnnouncement)
+	router.PATCH("/announcements/:id", middlewares.Authenticate, announcementHandler.UpdateAnnouncement)
+	router.GET("/blacklist", middlewares.Authenticate, middlewares.RequirePermission(models.PermReadBlacklist), userHandler.GetBlacklistChanges)
+	router.POST("/users/:id/blacklist", middlewares.Authenticate, middlewares.RequirePermission(models.PermManageBlacklist), userHandler.BlacklistUser)
+	router.DELETE("/users/:id/blacklist", middlewares.Authenticate, middlewares.RequirePermission(models.PermManageBlacklist), userHandler.UnblacklistUser)
+
+	db.TruncateUsersTable()
+
+	admin, adminToken := createTestUser(t, "admin@gmail.com", "+250781475100", models.RoleAdmin)
+	advertiser, advertiserToken := createTestUser(t, "advertiser@gmail.com", "+250781475101")
+	announcement := createTestAnnouncement(t, advertiser.ID, models.Pending)
+	userPath := "/users/" + strconv.FormatInt(advertiser.ID, 10) + "/blacklist"
+	announcementBody := `{
+		"end_date": "2030-01-01T15:30:00.000Z",
+		"start_date": "2030-01-01T13:30:00.000Z",
+		"text": "Blacklisted announcement"
+	}`
+
+	t.Run("Reason is required", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, userPath, adminToken, `{}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Expiry must be in the future", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, userPath, adminToken, `{"reason": "Spam", "expires_at": "2001-01-01T00:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/999999/blacklist", adminToken, `{"reason": "Spam"}`)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Advertisers cannot blacklist", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, userPath, advertiserToken, `{"reason": "Spam"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Admin blacklists", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, userPath, adminToken, `{"reason": "Repeated spam"}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+
+	t.Run("Blacklisted user cannot create", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements", advertiserToken, announcementBody)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Repeated spam")
+	})
+
+	t.Run("Blacklisted user cannot update", func(t *testing.T) {
+		path := "/announcements/" + strconv.FormatInt(announcement.ID, 10)
+		resp := testRequest(router, http.MethodPatch, path, advertiserToken, `{"text": "Sneaky edit"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Repeated spam")
+	})
+
+	t.Run("Admin removes from blacklist", func(t *testing.T) {
+		resp := testRequest(router, http.MethodDelete, userPath, adminToken, `{"reason": "Appeal accepted"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = testRequest(router, http.MethodDelete, userPath, adminToken, "")
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("User can create again", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements", advertiserToken, announcementBody)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+
+	t.Run("Expired blacklisting no longer applies", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Minute)
+		_, err := models.BlacklistUser(context.Background(), userHandler.Blacklist, advertiser.ID, admin.ID, "Short ban", &expiresAt)
+		assert.NoError(t, err)
+
+		resp := testRequest(router, http.MethodPost, "/announcements", advertiserToken, announcementBody)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+
+	t.Run("Changes are listed with who made them", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, "/blacklist?user_id="+strconv.FormatInt(advertiser.ID, 10), adminToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var body struct {
+			Changes []models.BlacklistChange `json:"changes"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		if assert.Len(t, body.Changes, 3) {
+			assert.Equal(t, models.BlacklistRemoved, body.Changes[1].Action)
+			assert.Equal(t, "Appeal accepted", body.Changes[1].Reason)
+			assert.Equal(t, admin.ID, body.Changes[2].ChangedBy)
+			assert.Equal(t, models.BlacklistAdded, body.Changes[2].Action)
+		}
+	})
+}
+
+func TestBlacklistInMemory(t *testing.T) {
+	t.Parallel()
+	userHandler, announcementHandler, users, _ := memoryHandlers()
+	router := newTestRouter()
+	router.POST("/announcements", fakeAuthenticate, announcementHandler.CreateAnnouncement)
+	router.GET("/blacklist", fakeAuthenticate, middlewares.RequirePermission(models.PermReadBlacklist), userHandler.GetBlacklistChanges)
+	router.POST("/users/:id/blacklist", fakeAuthenticate, middlewares.RequirePermission(models.PermManageBlacklist), userHandler.BlacklistUser)
+	router.DELETE("/users/:id/blacklist", fakeAuthenticate, middlewares.RequirePermission(models.PermManageBlacklist), userHandler.UnblacklistUser)
+
+	advertiser := models.User{Email: "frank@gmail.com", Password: "1234", FirstName: "Frank", LastName: "User", PhoneNumber: "+250781475610", Address: "KG 23 ST"}
+	assert.NoError(t, advertiser.Save(context.Background(), users), "Failed to create test user")
+	assert.NoError(t, users.VerifyEmail(context.Background(), advertiser.ID, advertiser.Email))
+	adminToken := fakeToken(99, models.RoleAdmin)
+	advertiserToken := fakeToken(advertiser.ID, models.RoleAdvertiser)
+	userPath := "/users/" + strconv.FormatInt(advertiser.ID, 10) + "/blacklist"
+	announcementBody := `{
+		"end_date": "2030-01-01T15:30:00.000Z",
+		"start_date": "2030-01-01T13:30:00.000Z",
+		"text": "Blacklisted announcement"
+	}`
+
+	t.Run("Unknown user", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/999999/blacklist", adminToken, `{"reason": "Spam"}`)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Blacklisted user cannot create", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, userPath, adminToken, `{"reason": "Repeated spam"}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		resp = testRequest(router, http.MethodPost, "/announcements", advertiserToken, announcementBody)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Repeated spam")
+	})
+
+	t.Run("User can create again once removed", func(t *testing.T) {
+		resp := testRequest(router, http.MethodDelete, userPath, adminToken, `{"reason": "Appeal accepted"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = testRequest(router, http.MethodDelete, userPath, adminToken, "")
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+
+		resp = testRequest(router, http.MethodPost, "/announcements", advertiserToken, announcementBody)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+
+	t.Run("Changes are listed newest first", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, "/blacklist?user_id="+strconv.FormatInt(advertiser.ID, 10), adminToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var body struct {
+			Changes []models.BlacklistChange `json:"changes"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		if assert.Len(t, body.Changes, 2) {
+			assert.Equal(t, models.BlacklistRemoved, body.Changes[0].Action)
+			assert.Equal(t, int64(99), body.Changes[1].ChangedBy)
+		}
+	})
+}
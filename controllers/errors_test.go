@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponses(t *testing.T) {
+	router := gin.Default()
+	router.Use(middlewares.RequestID, middlewares.HandleErrors)
+	router.PATCH("/announcements/:id/status", middlewares.Authenticate, middlewares.RequirePermission(models.PermModerateAnnouncements), announcementHandler.ChangeAnnouncementStatus)
+	router.GET("/broken", func(context *gin.Context) {
+		context.Error(fmt.Errorf("could not query: %w", errors.New("no such table: secrets")))
+	})
+
+	db.TruncateUsersTable()
+	owner, ownerToken := createTestUser(t, "problem-owner@gmail.com", "+250781475800")
+	_, moderatorToken := createTestUser(t, "problem-moderator@gmail.com", "+250781475801", models.RoleModerator)
+	announcement := createTestAnnouncement(t, owner.ID, models.Pending)
+
+	problem := func(t *testing.T, resp *httptest.ResponseRecorder) map[string]any {
+		assert.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		return body
+	}
+
+	t.Run("Domain errors become problems with a stable code", func(t *testing.T) {
+		path := fmt.Sprintf("/announcements/%d/status", announcement.ID)
+		resp := testRequest(router, http.MethodPatch, path, moderatorToken, `{"status": "active"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+
+		body := problem(t, resp)
+		assert.Equal(t, "about:blank", body["type"])
+		assert.Equal(t, "Conflict", body["title"])
+		assert.Equal(t, float64(http.StatusConflict), body["status"])
+		assert.Equal(t, "invalid_transition", body["code"])
+		assert.Equal(t, "Cannot change status from pending to active", body["detail"])
+		assert.Equal(t, path, body["instance"])
+		assert.Equal(t, []any{"accepted", "declined"}, body["allowed"])
+		assert.NotEmpty(t, body["request_id"])
+		assert.Equal(t, body["request_id"], resp.Header().Get("X-Request-ID"))
+		assert.NotContains(t, body, "fields")
+	})
+
+	t.Run("Middleware errors are problems too", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPatch, "/announcements/1/status", ownerToken, `{"status": "accepted"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Equal(t, "insufficient_permissions", problem(t, resp)["code"])
+
+		resp = testRequest(router, http.MethodPatch, "/announcements/1/status", "", `{"status": "accepted"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Equal(t, "missing_credentials", problem(t, resp)["code"])
+	})
+
+	t.Run("The request ID sent by the client is kept", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, "/broken", "", map[string]string{"X-Request-ID": "client-chosen-id"})
+		assert.Equal(t, "client-chosen-id", resp.Header().Get("X-Request-ID"))
+		assert.Equal(t, "client-chosen-id", problem(t, resp)["request_id"])
+	})
+
+	t.Run("Unknown errors are logged but not shown", func(t *testing.T) {
+		var logged strings.Builder
+		log.SetOutput(&logged)
+		defer log.SetOutput(os.Stderr)
+
+		resp := testRequest(router, http.MethodGet, "/broken", "", "")
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		body := problem(t, resp)
+		assert.Equal(t, "internal_error", body["code"])
+		assert.NotContains(t, resp.Body.String(), "secrets")
+
+		assert.Contains(t, logged.String(), "no such table: secrets")
+		assert.Contains(t, logged.String(), body["request_id"])
+	})
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// FlagAnnouncement godoc
+// @Summary Flag an announcement
+// @Description Report an announcement as inappropriate. Requires the flags:create permission, which auditors lack. Each user can flag an announcement once. An active announcement that reaches the flag threshold is deactivated pending review.
+// @Tags Flags
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Announcement ID"
+// @Param flag body models.FlagRequest true "Reason and optional description"
+// @Success 201 {object} utils.FlagSuccessResponse "Announcement flagged successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid announcement ID, request body or reason"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "Announcement not found"
+// @Failure 409 {object} utils.ErrorResponse "Announcement already flagged by this user"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /announcements/{id}/flags [post]
+func (h *AnnouncementHandler) FlagAnnouncement(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_announcement_id", "Invalid announcement ID"))
+		return
+	}
+
+	var request models.FlagRequest
+	err = context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse request body"))
+		return
+	}
+	if !request.Reason.IsValid() {
+		context.Error(models.BadRequest("invalid_flag_reason", "Invalid flag reason").With("allowed", models.FlagReasons))
+		return
+	}
+
+	userId := context.GetInt64("userId")
+	announcement, err := h.Announcements.GetByID(context.Request.Context(), id)
+	if err != nil || !announcement.IsVisibleTo(userId, callerCan(context, models.PermReadAllAnnouncements)) {
+		context.Error(models.NotFound("announcement_not_found", "Announcement not found"))
+		return
+	}
+
+	flag := models.Flag{
+		AnnouncementID: announcement.ID,
+		UserID:         userId,
+		Reason:         request.Reason,
+		Description:    request.Description,
+	}
+	err = flag.Create(context.Request.Context(), h.Flags)
+	if err != nil {
+		context.Error(fmt.Errorf("could not flag the announcement: %w", err))
+		return
+	}
+
+	// The flag is recorded either way; failing to deactivate must not fail the request
+	_, err = announcement.DeactivateIfFlagged(context.Request.Context(), h.Announcements, h.Flags, config.Int("FLAG_THRESHOLD", 3))
+	if err != nil {
+		log.Printf("Could not deactivate flagged announcement %d: %v", announcement.ID, err)
+	}
+
+	context.JSON(http.StatusCreated, gin.H{"message": "Announcement flagged successfully", "flag": flag})
+}
+
+// GetFlags godoc
+// @Summary Get flags
+// @Description List flags, newest first, optionally narrowed to a status or an announcement. Requires the flags:read permission.
+// @Tags Flags
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param status query string false "Only flags with this status" Enums(open, resolved, dismissed)
+// @Param announcement_id query int false "Only flags of this announcement"
+// @Success 200 {object} []models.Flag "Flags retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid query parameter"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch flags"
+// @Router /flags [get]
+func (h *AnnouncementHandler) GetFlags(context *gin.Context) {
+	status := models.FlagStatus(context.Query("status"))
+	if status != "" && status != models.FlagOpen && status != models.FlagResolved && status != models.FlagDismissed {
+		context.Error(models.BadRequest("invalid_query", "invalid status"))
+		return
+	}
+
+	var announcementID int64
+	if value := context.Query("announcement_id"); value != "" {
+		var err error
+		announcementID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			context.Error(models.BadRequest("invalid_query", "invalid announcement_id"))
+			return
+		}
+	}
+
+	flags, err := h.Flags.List(context.Request.Context(), status, announcementID)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch flags: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"flags": flags, "message": "Flags retrieved successfully"})
+}
+
+// GetFlagCounts godoc
+// @Summary Get flag counts per announcement
+// @Description Count the open and total flags of every flagged announcement, most open flags first. Requires the flags:read permission.
+// @Tags Flags
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} []models.FlagCount "Flag counts retrieved successfully"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch flag counts"
+// @Router /flags/counts [get]
+func (h *AnnouncementHandler) GetFlagCounts(context *gin.Context) {
+	counts, err := h.Flags.Counts(context.Request.Context())
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch flag counts: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"counts": counts, "message": "Flag counts retrieved successfully"})
+}
+
+// ResolveFlag godoc
+// @Summary Resolve or dismiss a flag
+// @Description Close an open flag, either as resolved (the report was acted upon) or dismissed. Requires the flags:resolve permission.
+// @Tags Flags
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Flag ID"
+// @Param resolution body models.FlagResolutionRequest true "resolved or dismissed"
+// @Success 200 {object} utils.FlagSuccessResponse "Flag updated successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid flag ID, request body or status"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "Flag not found"
+// @Failure 409 {object} utils.ErrorResponse "Flag is not open"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /flags/{id} [patch]
+func (h *AnnouncementHandler) ResolveFlag(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_flag_id", "Invalid flag ID"))
+		return
+	}
+
+	var request models.FlagResolutionRequest
+	err = context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse request body"))
+		return
+	}
+
+	flag, err := h.Flags.GetByID(context.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		context.Error(models.NotFound("flag_not_found", "Flag not found"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch the flag: %w", err))
+		return
+	}
+
+	err = flag.Resolve(context.Request.Context(), h.Flags, request.Status, context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not update the flag: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Flag updated successfully", "flag": flag})
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagAnnouncement(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/announcements/:id/flags", middlewares.Authenticate, middlewares.RequirePermission(models.PermCreateFlags), announcementHandler.FlagAnnouncement)
+	router.GET("/flags", middlewares.Authenticate, middlewares.RequirePermission(models.PermReadFlags), announcementHandler.GetFlags)
+	router.GET("/flags/counts", middlewares.Authenticate, middlewares.RequirePermission(models.PermReadFlags), announcementHandler.GetFlagCounts)
+	router.PATCH("/flags/:id", middlewares.Authenticate, middlewares.RequirePermission(models.PermResolveFlags), announcementHandler.ResolveFlag)
+
+	t.Setenv("FLAG_THRESHOLD", "2")
+	db.TruncateUsersTable()
+	db.TruncateFlagsTable()
+
+	_, adminToken := createTestUser(t, "admin@gmail.com", "+250781475100", models.RoleAdmin)
+	_, firstToken := createTestUser(t, "first@gmail.com", "+250781475101")
+	_, secondToken := createTestUser(t, "second@gmail.com", "+250781475102")
+	auditor, auditorToken := createTestUser(t, "auditor@gmail.com", "+250781475103", models.RoleAuditor)
+	announcement := createTestAnnouncement(t, 1, models.Active)
+	hidden := createTestAnnouncement(t, 1, models.Pending)
+	id := strconv.FormatInt(announcement.ID, 10)
+
+	t.Run("Unknown reason", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements/"+id+"/flags", firstToken, `{"reason": "boring"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Invalid flag reason")
+	})
+
+	t.Run("Cannot flag what you cannot see", func(t *testing.T) {
+		path := "/announcements/" + strconv.FormatInt(hidden.ID, 10) + "/flags"
+		resp := testRequest(router, http.MethodPost, path, firstToken, `{"reason": "spam"}`)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Auditors cannot flag", func(t *testing.T) {
+		// Without the advertiser role every user signs up with, an auditor is read-only
+		assert.NoError(t, sqlUsers.RevokeRole(context.Background(), auditor.ID, models.RoleAdvertiser))
+		resp := testRequest(router, http.MethodPost, "/announcements/"+id+"/flags", auditorToken, `{"reason": "spam"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"permission":"flags:create"`)
+	})
+
+	var flagID int64
+	t.Run("First flag", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements/"+id+"/flags", firstToken, `{"reason": "bad_language", "description": "Swearing"}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		var body struct {
+			Flag models.Flag `json:"flag"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, models.FlagOpen, body.Flag.Status)
+		flagID = body.Flag.ID
+
+		current, err := sqlAnnouncements.GetByID(context.Background(), announcement.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.Active, current.Status, "One flag is below the threshold")
+	})
+
+	t.Run("One flag per user", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements/"+id+"/flags", firstToken, `{"reason": "spam"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("Threshold deactivates the announcement", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements/"+id+"/flags", secondToken, `{"reason": "racist"}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		current, err := sqlAnnouncements.GetByID(context.Background(), announcement.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.Deactivated, current.Status)
+
+		changes, err := sqlAnnouncements.StatusChanges(context.Background(), announcement.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, changes, 1) {
+			assert.Equal(t, models.SystemUserID, changes[0].ChangedBy)
+		}
+	})
+
+	t.Run("Advertisers cannot list flags", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, "/flags", firstToken, "")
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Admin lists open flags", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, "/flags?status=open&announcement_id="+id, adminToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var body struct {
+			Flags []models.Flag `json:"flags"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Len(t, body.Flags, 2)
+	})
+
+	t.Run("Admin dismisses a flag", func(t *testing.T) {
+		path := "/flags/" + strconv.FormatInt(flagID, 10)
+		resp := testRequest(router, http.MethodPatch, path, adminToken, `{"status": "dismissed"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"status":"dismissed"`)
+
+		resp = testRequest(router, http.MethodPatch, path, adminToken, `{"status": "resolved"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("Flags cannot be reopened", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPatch, "/flags/"+strconv.FormatInt(flagID, 10), adminToken, `{"status": "open"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Admin sees counts", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, "/flags/counts", adminToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `{"announcement_id":`+id+`,"open":1,"total":2}`)
+	})
+}
+
+func TestFlagAnnouncementInMemory(t *testing.T) {
+	t.Parallel()
+	handler, announcements := memoryAnnouncementHandler()
+	router := newTestRouter()
+	router.POST("/announcements/:id/flags", fakeAuthenticate, middlewares.RequirePermission(models.PermCreateFlags), handler.FlagAnnouncement)
+	router.GET("/flags/counts", fakeAuthenticate, middlewares.RequirePermission(models.PermReadFlags), handler.GetFlagCounts)
+	router.PATCH("/flags/:id", fakeAuthenticate, middlewares.RequirePermission(models.PermResolveFlags), handler.ResolveFlag)
+
+	announcement := createMemoryAnnouncement(t, announcements, 1, models.Active)
+	path := "/announcements/" + strconv.FormatInt(announcement.ID, 10) + "/flags"
+	adminToken := fakeToken(99, models.RoleAdmin)
+
+	t.Run("One flag per user", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, path, fakeToken(2, models.RoleAdvertiser), `{"reason": "spam"}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		resp = testRequest(router, http.MethodPost, path, fakeToken(2, models.RoleAdvertiser), `{"reason": "spam"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("Default threshold deactivates the announcement", func(t *testing.T) {
+		for _, userId := range []int64{3, 4} {
+			resp := testRequest(router, http.MethodPost, path, fakeToken(userId, models.RoleAdvertiser), `{"reason": "racist"}`)
+			assert.Equal(t, http.StatusCreated, resp.Code)
+		}
+
+		current, err := announcements.GetByID(context.Background(), announcement.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.Deactivated, current.Status)
+	})
+
+	t.Run("Resolved flags are no longer open", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPatch, "/flags/1", adminToken, `{"status": "resolved"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = testRequest(router, http.MethodPatch, "/flags/1", adminToken, `{"status": "dismissed"}`)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+
+		resp = testRequest(router, http.MethodGet, "/flags/counts", adminToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"open":2,"total":3`)
+	})
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// UnlockUser godoc
+// @Summary Unlock a user
+// @Description Clear the failed login attempts of a user, lifting a lockout before it expires. Requires the users:unlock permission.
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.MessageResponse "User unlocked successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	user, err := h.Users.GetByID(context.Request.Context(), id)
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	err = lockout.Accounts(h.Lockouts).Reset(context.Request.Context(), lockout.AccountKey(user.Email))
+	if err != nil {
+		context.Error(fmt.Errorf("could not unlock the user: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
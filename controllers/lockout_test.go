@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Disable Gin's default writer to prevent log output
+	gin.DefaultWriter = io.Discard
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.POST("/users/login", userHandler.Login)
+	router.POST("/users/:id/unlock", middlewares.Authenticate, middlewares.RequirePermission(models.PermUnlockUsers), userHandler.UnlockUser)
+
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "3")
+	t.Setenv("LOGIN_IP_LOCKOUT_THRESHOLD", "5")
+	db.TruncateUsersTable()
+	_, adminToken := createTestUser(t, "admin@gmail.com", "+250781475100", models.RoleAdmin)
+	victim, _ := createTestUser(t, "victim@gmail.com", "+250781475101")
+
+	login := func(ip, email, password string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader(`{"email": "`+email+`", "password": "`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Account is locked after repeated failures", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusUnauthorized, login("10.0.0.1", "victim@gmail.com", "wrong").Code)
+		}
+
+		// Even the right password is refused, from any address
+		resp := login("10.0.0.2", "Victim@gmail.com", "1234")
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		retryAfter, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+		assert.NoError(t, err)
+		assert.InDelta(t, 60, retryAfter, 1)
+	})
+
+	t.Run("Admin unlocks the account", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/users/"+strconv.FormatInt(victim.ID, 10)+"/unlock", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		assert.Equal(t, http.StatusOK, login("10.0.0.2", "victim@gmail.com", "1234").Code)
+	})
+
+	t.Run("Client IP is locked after failures on many accounts", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusUnauthorized, login("10.0.0.3", "user"+strconv.Itoa(i)+"@gmail.com", "wrong").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, login("10.0.0.3", "victim@gmail.com", "1234").Code)
+		assert.Equal(t, http.StatusOK, login("10.0.0.4", "victim@gmail.com", "1234").Code)
+	})
+}
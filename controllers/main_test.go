@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// The tests that go through middlewares.Authenticate, or that check what the SQL stores write, share api.db
+// through these handlers, and so cannot run in parallel. The others use memoryHandlers.
+var (
+	sqlUsers            models.SQLUserStore
+	sqlAnnouncements    models.SQLAnnouncementStore
+	userHandler         *UserHandler
+	announcementHandler *AnnouncementHandler
+)
+
+func TestMain(m *testing.M) {
+	// Set once here, since tests running in parallel must not write them
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	db.InitDB()
+	sqlUsers = models.SQLUserStore{DB: db.DB}
+	sqlAnnouncements = models.SQLAnnouncementStore{DB: db.DB}
+	sessions := models.SQLSessionStore{DB: db.DB}
+	apiKeys := models.SQLAPIKeyStore{DB: db.DB}
+	twoFactor := models.SQLTwoFactorStore{DB: db.DB}
+	blacklist := models.SQLBlacklistStore{DB: db.DB}
+	flags := models.SQLFlagStore{DB: db.DB}
+	userHandler = &UserHandler{
+		Users:          sqlUsers,
+		Announcements:  sqlAnnouncements,
+		Sessions:       sessions,
+		APIKeys:        apiKeys,
+		TwoFactor:      twoFactor,
+		PasswordResets: models.SQLPasswordResetStore{DB: db.DB},
+		Blacklist:      blacklist,
+		Flags:          flags,
+		Lockouts:       lockout.SQLStore{DB: db.DB},
+	}
+	announcementHandler = &AnnouncementHandler{Announcements: sqlAnnouncements, Users: sqlUsers, Flags: flags, Blacklist: blacklist}
+	middlewares.Authenticators = []middlewares.Authenticator{
+		middlewares.JWTAuthenticator{Users: sqlUsers, Sessions: sessions, TwoFactor: twoFactor},
+	}
+	middlewares.APIKeys = middlewares.APIKeyAuthenticator{Users: sqlUsers, Keys: apiKeys, TwoFactor: twoFactor}
+
+	os.Exit(m.Run())
+}
+
+// memoryHandlers returns handlers whose stores belong to the calling test alone, so that the test can run in
+// parallel with the others. Both handlers share the stores, as they do in main.
+func memoryHandlers() (*UserHandler, *AnnouncementHandler, *models.MemoryUserStore, *models.MemoryAnnouncementStore) {
+	users := models.NewMemoryUserStore()
+	announcements := models.NewMemoryAnnouncementStore()
+	blacklist := models.NewMemoryBlacklistStore()
+	flags := models.NewMemoryFlagStore(announcements)
+	userHandler := &UserHandler{
+		Users:          users,
+		Announcements:  announcements,
+		Sessions:       models.NewMemorySessionStore(users),
+		APIKeys:        models.NewMemoryAPIKeyStore(),
+		TwoFactor:      models.NewMemoryTwoFactorStore(),
+		PasswordResets: models.NewMemoryPasswordResetStore(users),
+		Blacklist:      blacklist,
+		Flags:          flags,
+		Lockouts:       lockout.NewMemoryStore(),
+	}
+	announcementHandler := &AnnouncementHandler{Announcements: announcements, Users: users, Flags: flags, Blacklist: blacklist}
+	return userHandler, announcementHandler, users, announcements
+}
+
+// memoryUserHandler is memoryHandlers for the user endpoints
+func memoryUserHandler() (*UserHandler, *models.MemoryUserStore) {
+	handler, _, users, _ := memoryHandlers()
+	return handler, users
+}
+
+// memoryAnnouncementHandler is memoryHandlers for the announcement endpoints
+func memoryAnnouncementHandler() (*AnnouncementHandler, *models.MemoryAnnouncementStore) {
+	_, handler, _, announcements := memoryHandlers()
+	return handler, announcements
+}
+
+// createMemoryAnnouncement adds an announcement for the owner to the store and moderates it up to the status
+func createMemoryAnnouncement(t *testing.T, announcements models.AnnouncementStore, ownerID int64, status models.Status) models.Announcement {
+	announcement := models.Announcement{
+		OwnerID:   ownerID,
+		Text:      "Announcement in memory",
+		StartDate: time.Date(2030, 1, 1, 13, 30, 0, 0, time.UTC),
+		EndDate:   time.Date(2030, 1, 1, 15, 30, 0, 0, time.UTC),
+	}
+	assert.NoError(t, announcements.Create(context.Background(), &announcement), "Failed to create test announcement")
+
+	steps := map[models.Status][]models.Status{
+		models.Accepted:    {models.Accepted},
+		models.Declined:    {models.Declined},
+		models.Active:      {models.Accepted, models.Active},
+		models.Deactivated: {models.Accepted, models.Active, models.Deactivated},
+	}
+	for _, next := range steps[status] {
+		_, err := announcement.ChangeStatus(context.Background(), announcements, next, models.SystemUserID, "")
+		assert.NoError(t, err, "Failed to moderate test announcement")
+	}
+	return announcement
+}
+
+// fakeAuthenticate stands in for middlewares.Authenticate in tests on memory stores, since the authenticators
+// it runs are set up once for api.db. It trusts an Authorization header made by fakeToken, and leaves requests without
+// one anonymous.
+func fakeAuthenticate(context *gin.Context) {
+	fields := strings.Fields(context.GetHeader("Authorization"))
+	if len(fields) == 0 {
+		context.Next()
+		return
+	}
+	userId, _ := strconv.ParseInt(fields[0], 10, 64)
+	principal := &middlewares.Principal{UserID: userId, Method: "Bearer"}
+	for _, role := range fields[1:] {
+		principal.Roles = append(principal.Roles, models.Role(role))
+	}
+	context.Set("principal", principal)
+	context.Set("userId", userId)
+	context.Next()
+}
+
+// fakeToken names the caller and their roles for fakeAuthenticate
+func fakeToken(userId int64, roles ...models.Role) string {
+	token := strconv.FormatInt(userId, 10)
+	for _, role := range roles {
+		token += " " + string(role)
+	}
+	return token
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/ngirimana/AnnounceIT/notifications"
+)
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Send a single-use password reset token to the email address. The email is sent after responding, so that neither the response nor its timing reveals whether the address is registered.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Email of the account"
+// @Success 200 {object} utils.MessageResponse "If the email is registered, a reset token has been sent"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request"
+// @Router /users/password/forgot [post]
+func (h *UserHandler) ForgotPassword(context *gin.Context) {
+	var request models.ForgotPasswordRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+
+	// The account is looked up and emailed after responding, so that neither the response nor the time it
+	// takes reveals whether the address is registered
+	inBackground(func() { h.sendPasswordReset(request.Email) })
+
+	context.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a reset token has been sent"})
+}
+
+// sendPasswordReset emails a reset token to the account registered with the email, if any.
+// Failures are only logged, as there is nobody left to answer.
+func (h *UserHandler) sendPasswordReset(email string) {
+	user, err := h.Users.GetByEmail(context.Background(), email)
+	if err != nil {
+		return
+	}
+	ttl := config.Duration("PASSWORD_RESET_TTL", time.Hour)
+	token, err := models.CreatePasswordReset(context.Background(), h.PasswordResets, user.ID, ttl)
+	if err != nil {
+		log.Printf("Could not create password reset for user %d: %v", user.ID, err)
+	} else if err = notifications.Default.PasswordReset(user.Email, token); err != nil {
+		log.Printf("Could not send password reset to user %d: %v", user.ID, err)
+	}
+}
+
+// background tracks the work handlers go on with after responding, so that tests can wait for it
+var background sync.WaitGroup
+
+// inBackground runs work off the request path
+func inBackground(work func()) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		work()
+	}()
+}
+
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Set a new password using a reset token. The token can only be used once, and every token issued before the reset stops working.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} utils.MessageResponse "Password reset successfully"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request, or invalid or expired reset token"
+// @Failure 422 {object} utils.ValidationErrorResponse "The new password is too weak"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/password/reset [post]
+func (h *UserHandler) ResetPassword(context *gin.Context) {
+	var request models.ResetPasswordRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	if rejectWeakPassword(context, "password", request.Password) {
+		return
+	}
+
+	err = models.ResetPassword(context.Request.Context(), h.PasswordResets, request.Token, request.Password)
+	if err != nil {
+		context.Error(fmt.Errorf("could not reset the password: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// ChangePassword godoc
+// @Summary Change my password
+// @Description Replace the password of the authenticated user after checking the current one. Every token issued before is revoked, and a new access and refresh token are returned.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} utils.LoginSuccessResponse "Password changed successfully"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid, or current password is wrong"
+// @Failure 422 {object} utils.ValidationErrorResponse "The new password is too weak"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/password [put]
+func (h *UserHandler) ChangePassword(context *gin.Context) {
+	var request models.ChangePasswordRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	if rejectWeakPassword(context, "new_password", request.NewPassword) {
+		return
+	}
+
+	user, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	err = user.ChangePassword(context.Request.Context(), h.Users, request.CurrentPassword, request.NewPassword)
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		context.Error(models.Unauthorized("invalid_password", "Current password is incorrect"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not change the password: %w", err))
+		return
+	}
+
+	jwt, refreshToken, err := h.issueTokens(context, user)
+	if err != nil {
+		context.Error(fmt.Errorf("could not generate token: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Password changed successfully", "jwt": jwt, "refresh_token": refreshToken})
+}
+
+// rejectWeakPassword fails the request with the reason when a new password, sent as field, is too weak,
+// and reports whether it did
+func rejectWeakPassword(context *gin.Context, field, password string) bool {
+	problem, err := helpers.CheckPasswordStrength(password)
+	if err != nil {
+		context.Error(fmt.Errorf("could not check the password: %w", err))
+		return true
+	}
+	if problem != "" {
+		invalidField(context, models.FieldError{Field: field, Code: "weak_password", Message: problem})
+		return true
+	}
+	return false
+}
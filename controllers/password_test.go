@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/ngirimana/AnnounceIT/notifications"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingNotifier keeps the last reset token instead of sending it
+type recordingNotifier struct {
+	mu     sync.Mutex
+	resets map[string]string
+}
+
+func (n *recordingNotifier) PasswordReset(email, token string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.resets[email] = token
+	return nil
+}
+
+func (n *recordingNotifier) EmailVerification(email, token string) error {
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Disable Gin's default writer to prevent log output
+	gin.DefaultWriter = io.Discard
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.POST("/users/login", userHandler.Login)
+	router.POST("/users/password/forgot", userHandler.ForgotPassword)
+	router.POST("/users/password/reset", userHandler.ResetPassword)
+	router.GET("/users/me/announcements", middlewares.Authenticate, announcementHandler.GetMyAnnouncements)
+
+	notifier := &recordingNotifier{resets: map[string]string{}}
+	previous := notifications.Default
+	notifications.Default = notifier
+	defer func() { notifications.Default = previous }()
+
+	db.TruncateUsersTable()
+	_, oldToken := createTestUser(t, "reset@gmail.com", "+250781475102")
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// The forgot endpoint must not reveal which addresses are registered
+	known := post("/users/password/forgot", `{"email": "reset@gmail.com"}`)
+	unknown := post("/users/password/forgot", `{"email": "nobody@gmail.com"}`)
+	assert.Equal(t, http.StatusOK, known.Code)
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+	background.Wait()
+	assert.NotContains(t, notifier.resets, "nobody@gmail.com")
+
+	resetToken := notifier.resets["reset@gmail.com"]
+	assert.NotEmpty(t, resetToken)
+
+	t.Run("Bad request", func(t *testing.T) {
+		resp := post("/users/password/reset", `{"token": "abc"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), "could not parse the request")
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		resp := post("/users/password/reset", `{"token": "abc", "password": "new-password"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Invalid or expired reset token")
+	})
+
+	t.Run("Weak password", func(t *testing.T) {
+		resp := post("/users/password/reset", `{"token": "`+resetToken+`", "password": "short"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		assert.Contains(t, resp.Body.String(), `{"field":"password","code":"weak_password"`)
+		assert.Contains(t, resp.Body.String(), "at least 8 characters")
+	})
+
+	t.Run("Successful reset", func(t *testing.T) {
+		resp := post("/users/password/reset", `{"token": "`+resetToken+`", "password": "new-password"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Password reset successfully")
+	})
+
+	t.Run("Token is single-use", func(t *testing.T) {
+		resp := post("/users/password/reset", `{"token": "`+resetToken+`", "password": "another-password"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Previously issued JWTs are revoked", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/users/me/announcements", nil)
+		req.Header.Set("Authorization", oldToken)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Token has been revoked")
+	})
+
+	t.Run("Login uses the new password", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, post("/users/login", `{"email": "reset@gmail.com", "password": "1234"}`).Code)
+		assert.Equal(t, http.StatusOK, post("/users/login", `{"email": "reset@gmail.com", "password": "new-password"}`).Code)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		user, err := sqlUsers.GetByEmail(context.Background(), "reset@gmail.com")
+		assert.NoError(t, err)
+		expired, err := models.CreatePasswordReset(context.Background(), userHandler.PasswordResets, user.ID, -time.Minute)
+		assert.NoError(t, err)
+
+		resp := post("/users/password/reset", `{"token": "`+expired+`", "password": "new-password"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Disable Gin's default writer to prevent log output
+	gin.DefaultWriter = io.Discard
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.PUT("/users/me/password", middlewares.Authenticate, userHandler.ChangePassword)
+
+	db.TruncateUsersTable()
+	_, token := createTestUser(t, "change@gmail.com", "+250781475103")
+
+	put := func(token, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPut, "/users/me/password", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Missing new password", func(t *testing.T) {
+		resp := put(token, `{"current_password": "1234"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		resp := put(token, `{"current_password": "wrong", "new_password": "new-password"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Current password is incorrect")
+	})
+
+	t.Run("Weak new password", func(t *testing.T) {
+		resp := put(token, `{"current_password": "1234", "new_password": "short"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		assert.Contains(t, resp.Body.String(), `{"field":"new_password","code":"weak_password"`)
+		assert.Contains(t, resp.Body.String(), "at least 8 characters")
+	})
+
+	t.Run("Breached new password", func(t *testing.T) {
+		list := filepath.Join(t.TempDir(), "breached.txt")
+		assert.NoError(t, os.WriteFile(list, []byte("password1\nIloveyou123\n"), 0o600))
+		t.Setenv("PASSWORD_BREACHED_LIST", list)
+
+		resp := put(token, `{"current_password": "1234", "new_password": "iloveyou123"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		assert.Contains(t, resp.Body.String(), "data breach")
+	})
+
+	var newToken string
+	t.Run("Successful change", func(t *testing.T) {
+		resp := put(token, `{"current_password": "1234", "new_password": "new-password"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		newToken, _ = body["jwt"].(string)
+		assert.NotEmpty(t, newToken)
+	})
+
+	t.Run("Old token is revoked", func(t *testing.T) {
+		resp := put(token, `{"current_password": "new-password", "new_password": "1234"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Token has been revoked")
+	})
+
+	t.Run("New token works", func(t *testing.T) {
+		resp := put(newToken, `{"current_password": "new-password", "new_password": "newer-password"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+}
+
+func TestPasswordRehash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Disable Gin's default writer to prevent log output
+	gin.DefaultWriter = io.Discard
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.POST("/users/login", userHandler.Login)
+
+	db.TruncateUsersTable()
+
+	storedHash := func(email string) string {
+		var hash string
+		assert.NoError(t, db.DB.QueryRow("SELECT password FROM users WHERE email = ?", email).Scan(&hash))
+		return hash
+	}
+	login := func() int {
+		req, _ := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader(`{"email": "rehash@gmail.com", "password": "1234"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// Registered first so that it runs after the environment is restored
+	t.Cleanup(func() { assert.NoError(t, helpers.CheckPasswordHasher()) })
+
+	// Users created before argon2id have bcrypt hashes
+	t.Setenv("PASSWORD_HASHER", "bcrypt")
+	t.Setenv("BCRYPT_COST", "4")
+	assert.NoError(t, helpers.CheckPasswordHasher())
+	createTestUser(t, "rehash@gmail.com", "+250781475104")
+	assert.True(t, strings.HasPrefix(storedHash("rehash@gmail.com"), "$2a$04$"))
+
+	t.Run("Outdated bcrypt cost is raised", func(t *testing.T) {
+		t.Setenv("BCRYPT_COST", "5")
+		assert.NoError(t, helpers.CheckPasswordHasher())
+		assert.Equal(t, http.StatusOK, login())
+		assert.True(t, strings.HasPrefix(storedHash("rehash@gmail.com"), "$2a$05$"))
+	})
+
+	t.Run("bcrypt is replaced by argon2id", func(t *testing.T) {
+		t.Setenv("PASSWORD_HASHER", "argon2id")
+		t.Setenv("ARGON2_MEMORY_KIB", "1024")
+		assert.NoError(t, helpers.CheckPasswordHasher())
+		assert.Equal(t, http.StatusOK, login())
+		assert.True(t, strings.HasPrefix(storedHash("rehash@gmail.com"), "$argon2id$v=19$m=1024,t=3,p=2$"))
+	})
+
+	t.Run("Changed argon2id parameters are applied", func(t *testing.T) {
+		t.Setenv("PASSWORD_HASHER", "argon2id")
+		t.Setenv("ARGON2_MEMORY_KIB", "2048")
+		t.Setenv("ARGON2_ITERATIONS", "2")
+		assert.NoError(t, helpers.CheckPasswordHasher())
+		assert.Equal(t, http.StatusOK, login())
+		hash := storedHash("rehash@gmail.com")
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=2048,t=2,p=2$"))
+
+		// Logging in again with the same parameters leaves the hash alone
+		assert.Equal(t, http.StatusOK, login())
+		assert.Equal(t, hash, storedHash("rehash@gmail.com"))
+	})
+
+	t.Run("Out of range parameters are refused", func(t *testing.T) {
+		for name, value := range map[string]string{
+			"ARGON2_PARALLELISM": "256",
+			"ARGON2_ITERATIONS":  "0",
+			"ARGON2_MEMORY_KIB":  "4",
+			"BCRYPT_COST":        "32",
+			"PASSWORD_HASHER":    "md5",
+		} {
+			t.Run(name, func(t *testing.T) {
+				t.Setenv(name, value)
+				assert.Error(t, helpers.CheckPasswordHasher())
+			})
+		}
+	})
+}
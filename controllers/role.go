@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// GrantRole godoc
+// @Summary Grant a role
+// @Description Give a user one of the roles advertiser, moderator, admin or auditor. Granting a role the user already has does nothing. Requires the roles:manage permission.
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param request body models.RoleRequest true "Role to grant"
+// @Success 200 {object} utils.RolesSuccessResponse "Role granted successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID, request body or role"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/{id}/roles [post]
+func (h *UserHandler) GrantRole(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	var request models.RoleRequest
+	err = context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	if !request.Role.IsValid() {
+		context.Error(models.BadRequest("unknown_role", "Unknown role").With("allowed", models.Roles))
+		return
+	}
+
+	user, err := h.Users.GetByID(context.Request.Context(), id)
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	err = h.Users.GrantRole(context.Request.Context(), user.ID, request.Role, context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not grant the role: %w", err))
+		return
+	}
+
+	h.respondWithRoles(context, user.ID, "Role granted successfully")
+}
+
+// RevokeRole godoc
+// @Summary Revoke a role
+// @Description Take a role away from a user. The admin role cannot be revoked from the last admin. Requires the roles:manage permission.
+// @Tags Roles
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param role path string true "Role" Enums(advertiser, moderator, admin, auditor)
+// @Success 200 {object} utils.RolesSuccessResponse "Role revoked successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID or role"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "User does not have this role"
+// @Failure 409 {object} utils.ErrorResponse "Cannot revoke the admin role from the last admin"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/{id}/roles/{role} [delete]
+func (h *UserHandler) RevokeRole(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	err = h.Users.RevokeRole(context.Request.Context(), id, models.Role(context.Param("role")))
+	switch {
+	case errors.Is(err, models.ErrUnknownRole):
+		context.Error(models.BadRequest("unknown_role", "Unknown role").With("allowed", models.Roles))
+		return
+	case errors.Is(err, models.ErrRoleNotGranted):
+		context.Error(models.NotFound("role_not_granted", "User does not have this role"))
+		return
+	case errors.Is(err, models.ErrLastAdmin):
+		context.Error(models.Conflict("last_admin", "Cannot revoke the admin role from the last admin"))
+		return
+	case err != nil:
+		context.Error(fmt.Errorf("could not revoke the role: %w", err))
+		return
+	}
+
+	h.respondWithRoles(context, id, "Role revoked successfully")
+}
+
+func (h *UserHandler) respondWithRoles(context *gin.Context, userId int64, message string) {
+	roles, err := h.Users.Roles(context.Request.Context(), userId)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch roles: %w", err))
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": message, "user_id": userId, "roles": roles})
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoles(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/signup", userHandler.SignUp)
+	router.POST("/users/:id/roles", middlewares.Authenticate, middlewares.RequirePermission(models.PermManageRoles), userHandler.GrantRole)
+	router.DELETE("/users/:id/roles/:role", middlewares.Authenticate, middlewares.RequirePermission(models.PermManageRoles), userHandler.RevokeRole)
+	router.PATCH("/announcements/:id/status", middlewares.Authenticate, middlewares.RequirePermission(models.PermModerateAnnouncements), announcementHandler.ChangeAnnouncementStatus)
+
+	db.TruncateUsersTable()
+
+	admin, adminToken := createTestUser(t, "admin@gmail.com", "+250781475100", models.RoleAdmin)
+	advertiser, advertiserToken := createTestUser(t, "advertiser@gmail.com", "+250781475101")
+	_, auditorToken := createTestUser(t, "auditor@gmail.com", "+250781475102", models.RoleAuditor)
+	announcement := createTestAnnouncement(t, advertiser.ID, models.Pending)
+	statusPath := "/announcements/" + strconv.FormatInt(announcement.ID, 10) + "/status"
+	rolesPath := "/users/" + strconv.FormatInt(advertiser.ID, 10) + "/roles"
+
+	t.Run("Signup ignores is_admin", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/signup", "", `{
+			"email": "sneaky@gmail.com",
+			"password": "correct-horse-42",
+			"first_name": "Sneaky",
+			"last_name": "User",
+			"phone_number": "+250781475103",
+			"address": "KG 23 ST",
+			"is_admin": true,
+			"roles": ["admin"]
+		}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		user, err := sqlUsers.GetByEmail(context.Background(), "sneaky@gmail.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Role{models.RoleAdvertiser}, user.Roles)
+	})
+
+	t.Run("Advertisers cannot moderate", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPatch, statusPath, advertiserToken, `{"status": "accepted"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"permission":"announcements:moderate"`)
+	})
+
+	t.Run("Auditors are read-only", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPatch, statusPath, auditorToken, `{"status": "accepted"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Only admins manage roles", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, rolesPath, advertiserToken, `{"role": "admin"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, rolesPath, adminToken, `{"role": "superuser"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Admin grants moderator", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, rolesPath, adminToken, `{"role": "moderator"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var body struct {
+			Roles []models.Role `json:"roles"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, []models.Role{models.RoleAdvertiser, models.RoleModerator}, body.Roles)
+	})
+
+	t.Run("Granted role applies to existing tokens", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPatch, statusPath, advertiserToken, `{"status": "accepted"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Admin revokes moderator", func(t *testing.T) {
+		resp := testRequest(router, http.MethodDelete, rolesPath+"/moderator", adminToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = testRequest(router, http.MethodDelete, rolesPath+"/moderator", adminToken, "")
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+
+		resp = testRequest(router, http.MethodPatch, statusPath, advertiserToken, `{"status": "active"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("The last admin is kept", func(t *testing.T) {
+		path := "/users/" + strconv.FormatInt(admin.ID, 10) + "/roles/admin"
+		resp := testRequest(router, http.MethodDelete, path, adminToken, "")
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+}
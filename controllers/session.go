@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// GetSessions godoc
+// @Summary Get my sessions
+// @Description List the devices the authenticated user is logged in on, most recently seen first. The session of the token used for this request is marked as current.
+// @Tags Sessions
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} []models.Session "Sessions retrieved successfully"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "The request was made with an API key"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch sessions"
+// @Router /users/me/sessions [get]
+func (h *UserHandler) GetSessions(context *gin.Context) {
+	sessions, err := h.Sessions.List(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch sessions: %w", err))
+		return
+	}
+
+	current := middlewares.CurrentPrincipal(context).SessionID
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Sessions retrieved successfully", "sessions": sessions})
+}
+
+// RevokeSession godoc
+// @Summary Sign out a device
+// @Description Revoke one of your sessions, for instance on a lost device. Its access and refresh tokens stop working immediately.
+// @Tags Sessions
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "Session ID"
+// @Success 200 {object} utils.MessageResponse "Session revoked successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid session ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "The request was made with an API key"
+// @Failure 404 {object} utils.ErrorResponse "Session not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/sessions/{id} [delete]
+func (h *UserHandler) RevokeSession(context *gin.Context) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_session_id", "Invalid session ID"))
+		return
+	}
+
+	err = h.Sessions.Revoke(context.Request.Context(), context.GetInt64("userId"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		context.Error(models.NotFound("session_not_found", "Session not found"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not revoke the session: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/login", userHandler.Login)
+	router.POST("/users/token/refresh", userHandler.RefreshToken)
+	router.POST("/users/logout", middlewares.Authenticate, userHandler.Logout)
+	router.GET("/users/me/sessions", middlewares.Authenticate, userHandler.GetSessions)
+	router.DELETE("/users/me/sessions/:id", middlewares.Authenticate, userHandler.RevokeSession)
+
+	db.TruncateUsersTable()
+	_, setupToken := createTestUser(t, "sessions@gmail.com", "+250781475400")
+	_, otherToken := createTestUser(t, "other-sessions@gmail.com", "+250781475401")
+
+	type tokens struct {
+		JWT          string `json:"jwt"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	login := func(userAgent string) tokens {
+		resp := sendTestRequest(router, http.MethodPost, "/users/login", `{"email": "sessions@gmail.com", "password": "1234"}`, map[string]string{"User-Agent": userAgent})
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body tokens
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		return body
+	}
+	listSessions := func(token string) []models.Session {
+		resp := sendTestRequest(router, http.MethodGet, "/users/me/sessions", "", map[string]string{"Authorization": token, "User-Agent": "Phone"})
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Sessions []models.Session `json:"sessions"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		return body.Sessions
+	}
+
+	// Only the logins below are to be listed
+	assert.Equal(t, http.StatusOK, testRequest(router, http.MethodPost, "/users/logout", setupToken, "").Code)
+	phone := login("Phone")
+	laptop := login("Laptop")
+
+	var laptopSession int64
+	t.Run("Logins are listed as sessions", func(t *testing.T) {
+		sessions := listSessions(phone.JWT)
+		if assert.Len(t, sessions, 2) {
+			agents := map[string]models.Session{}
+			for _, session := range sessions {
+				agents[session.UserAgent] = session
+			}
+			assert.True(t, agents["Phone"].Current)
+			assert.False(t, agents["Laptop"].Current)
+			assert.Equal(t, "192.0.2.10", agents["Laptop"].IPAddress)
+			laptopSession = agents["Laptop"].ID
+		}
+	})
+
+	t.Run("Refreshing keeps the session", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodPost, "/users/token/refresh", `{"refresh_token": "`+phone.RefreshToken+`"}`, map[string]string{"User-Agent": "Phone"})
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &phone))
+		assert.Len(t, listSessions(phone.JWT), 2)
+	})
+
+	t.Run("Last seen is updated lazily", func(t *testing.T) {
+		recent := time.Now().UTC().Add(-10 * time.Second).Truncate(time.Second)
+		stale := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+		lastSeen := func() time.Time {
+			var at time.Time
+			assert.NoError(t, db.DB.QueryRow("SELECT last_seen_at FROM sessions WHERE id = ?", laptopSession).Scan(&at))
+			return at
+		}
+
+		_, err := db.DB.Exec("UPDATE sessions SET last_seen_at = ? WHERE id = ?", recent, laptopSession)
+		assert.NoError(t, err)
+		listSessions(laptop.JWT)
+		assert.True(t, lastSeen().Equal(recent))
+
+		_, err = db.DB.Exec("UPDATE sessions SET last_seen_at = ? WHERE id = ?", stale, laptopSession)
+		assert.NoError(t, err)
+		listSessions(laptop.JWT)
+		assert.True(t, lastSeen().After(recent))
+	})
+
+	path := "/users/me/sessions/" + strconv.FormatInt(laptopSession, 10)
+	t.Run("Other users cannot revoke the session", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodDelete, path, "", map[string]string{"Authorization": otherToken, "User-Agent": "Other"})
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Revoking signs the device out", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodDelete, path, "", map[string]string{"Authorization": phone.JWT, "User-Agent": "Phone"})
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodGet, "/users/me/sessions", "", map[string]string{"Authorization": laptop.JWT, "User-Agent": "Laptop"})
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Token has been revoked")
+
+		resp = sendTestRequest(router, http.MethodPost, "/users/token/refresh", `{"refresh_token": "`+laptop.RefreshToken+`"}`, map[string]string{"User-Agent": "Laptop"})
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+		assert.Len(t, listSessions(phone.JWT), 1)
+
+		resp = sendTestRequest(router, http.MethodDelete, path, "", map[string]string{"Authorization": phone.JWT, "User-Agent": "Phone"})
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Logout ends the session", func(t *testing.T) {
+		other := login("Tablet")
+		resp := sendTestRequest(router, http.MethodPost, "/users/logout", "", map[string]string{"Authorization": other.JWT, "User-Agent": "Tablet"})
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodPost, "/users/token/refresh", `{"refresh_token": "`+other.RefreshToken+`"}`, map[string]string{"User-Agent": "Tablet"})
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Len(t, listSessions(phone.JWT), 1)
+	})
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+func refreshTokenTTL() time.Duration {
+	return config.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// issueTokens starts a session for the device making the request, and returns a short-lived access token
+// and the first refresh token of the session
+func (h *UserHandler) issueTokens(context *gin.Context, user *models.User) (string, string, error) {
+	sessionId, refreshToken, err := h.Sessions.Start(context.Request.Context(), user.ID, user.TokenVersion, context.ClientIP(), context.Request.UserAgent(), refreshTokenTTL())
+	if err != nil {
+		return "", "", err
+	}
+	jwt, err := helpers.GenerateToken(user.Email, user.ID, user.TokenVersion, sessionId, user.RoleNames())
+	if err != nil {
+		return "", "", err
+	}
+	return jwt, refreshToken, nil
+}
+
+// RefreshToken godoc
+// @Summary Refresh the access token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token can only be used once; using one again revokes every token descended from the same login.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} utils.LoginSuccessResponse "Token refreshed successfully"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request"
+// @Failure 401 {object} utils.ErrorResponse "Invalid or expired refresh token, or refresh token reuse detected"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/token/refresh [post]
+func (h *UserHandler) RefreshToken(context *gin.Context) {
+	var request models.RefreshRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+
+	grant, err := h.Sessions.RotateRefreshToken(context.Request.Context(), request.RefreshToken, refreshTokenTTL())
+	if err != nil {
+		context.Error(fmt.Errorf("could not refresh the token: %w", err))
+		return
+	}
+
+	user, err := h.Users.GetByID(context.Request.Context(), grant.UserID)
+	if err != nil {
+		context.Error(fmt.Errorf("could not refresh the token: %w", err))
+		return
+	}
+	jwt, err := helpers.GenerateToken(user.Email, user.ID, user.TokenVersion, grant.SessionID, user.RoleNames())
+	if err != nil {
+		context.Error(fmt.Errorf("could not generate token: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Token refreshed successfully", "jwt": jwt, "refresh_token": grant.RefreshToken})
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke the access token used for this request and its session and, when it is sent, the refresh token issued with it.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.LogoutRequest false "Refresh token to revoke"
+// @Success 200 {object} utils.MessageResponse "Logged out successfully"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/logout [post]
+func (h *UserHandler) Logout(context *gin.Context) {
+	// The body is optional
+	var request models.LogoutRequest
+	if context.Request.ContentLength > 0 {
+		if err := context.ShouldBindJSON(&request); err != nil {
+			context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+			return
+		}
+	}
+
+	var err error
+	// Callers authenticated by other means than a JWT have no access token to revoke
+	principal := middlewares.CurrentPrincipal(context)
+	if principal.TokenID != "" {
+		err = h.Sessions.RevokeAccessToken(context.Request.Context(), principal.TokenID, principal.TokenExpiresAt)
+	}
+	if err == nil && principal.SessionID != 0 {
+		err = h.Sessions.Revoke(context.Request.Context(), principal.UserID, principal.SessionID)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+	}
+	if err == nil && request.RefreshToken != "" {
+		err = h.Sessions.RevokeRefreshToken(context.Request.Context(), context.GetInt64("userId"), request.RefreshToken)
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not log out: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll godoc
+// @Summary Log out everywhere
+// @Description Revoke every access and refresh token of the authenticated user, on every device.
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} utils.MessageResponse "Logged out of all sessions successfully"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/logout-all [post]
+func (h *UserHandler) LogoutAll(context *gin.Context) {
+	err := h.Sessions.RevokeAll(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not log out: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions successfully"})
+}
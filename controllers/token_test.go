@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshAndLogout(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/login", userHandler.Login)
+	router.POST("/users/token/refresh", userHandler.RefreshToken)
+	router.POST("/users/logout", middlewares.Authenticate, userHandler.Logout)
+	router.POST("/users/logout-all", middlewares.Authenticate, userHandler.LogoutAll)
+	router.GET("/users/me/announcements", middlewares.Authenticate, announcementHandler.GetMyAnnouncements)
+
+	db.TruncateUsersTable()
+	createTestUser(t, "refresh@gmail.com", "+250781475105")
+
+	type tokens struct {
+		JWT          string `json:"jwt"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	login := func() tokens {
+		resp := testRequest(router, http.MethodPost, "/users/login", "", `{"email": "refresh@gmail.com", "password": "1234"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body tokens
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.NotEmpty(t, body.RefreshToken)
+		return body
+	}
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		return testRequest(router, http.MethodPost, "/users/token/refresh", "", `{"refresh_token": "`+refreshToken+`"}`)
+	}
+	authorized := func(token string) int {
+		return testRequest(router, http.MethodGet, "/users/me/announcements", token, "").Code
+	}
+
+	first := login()
+	var second tokens
+	t.Run("Refresh rotates the refresh token", func(t *testing.T) {
+		resp := refresh(first.RefreshToken)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &second))
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+		assert.Equal(t, http.StatusOK, authorized(second.JWT))
+	})
+
+	t.Run("Unknown refresh token", func(t *testing.T) {
+		resp := refresh("not-a-token")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Reuse revokes the family", func(t *testing.T) {
+		resp := refresh(first.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "reuse detected")
+
+		resp = refresh(second.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Concurrent refreshes with one token count as reuse", func(t *testing.T) {
+		session := login()
+		codes := make([]int, 2)
+		var wg sync.WaitGroup
+		for i := range codes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes[i] = refresh(session.RefreshToken).Code
+			}()
+		}
+		wg.Wait()
+		assert.ElementsMatch(t, []int{http.StatusOK, http.StatusUnauthorized}, codes)
+	})
+
+	t.Run("Logout revokes the access and refresh token", func(t *testing.T) {
+		session := login()
+		resp := testRequest(router, http.MethodPost, "/users/logout", session.JWT, `{"refresh_token": "`+session.RefreshToken+`"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, authorized(session.JWT))
+		assert.Equal(t, http.StatusUnauthorized, refresh(session.RefreshToken).Code)
+	})
+
+	t.Run("Logout only affects its own session", func(t *testing.T) {
+		kept := login()
+		other := login()
+		resp := testRequest(router, http.MethodPost, "/users/logout", other.JWT, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		assert.Equal(t, http.StatusOK, authorized(kept.JWT))
+		assert.Equal(t, http.StatusOK, refresh(kept.RefreshToken).Code)
+	})
+
+	t.Run("Logout everywhere", func(t *testing.T) {
+		phone := login()
+		laptop := login()
+		resp := testRequest(router, http.MethodPost, "/users/logout-all", laptop.JWT, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, authorized(phone.JWT))
+		assert.Equal(t, http.StatusUnauthorized, authorized(laptop.JWT))
+		assert.Equal(t, http.StatusUnauthorized, refresh(phone.RefreshToken).Code)
+		assert.Equal(t, http.StatusOK, authorized(login().JWT))
+	})
+}
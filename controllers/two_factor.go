@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// SetupTwoFactor godoc
+// @Summary Start two-factor enrolment
+// @Description Generate a TOTP secret for the authenticated user. Add it to an authenticator app, usually by showing otpauth_uri as a QR code, then confirm with a code. Two-factor authentication is not active until confirmed.
+// @Tags Two-factor authentication
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} utils.TwoFactorSetupResponse "Two-factor setup started"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 409 {object} utils.ErrorResponse "Two-factor authentication is already enabled"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/2fa/setup [post]
+func (h *UserHandler) SetupTwoFactor(context *gin.Context) {
+	user, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	secret, err := models.BeginTOTPSetup(context.Request.Context(), h.TwoFactor, user.ID)
+	if errors.Is(err, models.ErrTwoFactorEnabled) {
+		context.Error(models.Conflict("two_factor_already_enabled", "Two-factor authentication is already enabled"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not start two-factor setup: %w", err))
+		return
+	}
+
+	uri := helpers.TOTPURI(config.String("TOTP_ISSUER", "AnnounceIT"), user.Email, secret)
+	context.JSON(http.StatusOK, gin.H{"message": "Two-factor setup started", "secret": secret, "otpauth_uri": uri})
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Confirm two-factor enrolment
+// @Description Turn on two-factor authentication with a code from the authenticator app. The response holds one-time recovery codes for when the app is lost; they are not shown again.
+// @Tags Two-factor authentication
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} utils.RecoveryCodesResponse "Two-factor authentication enabled"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request, or two-factor setup was not started"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "The code is wrong"
+// @Failure 409 {object} utils.ErrorResponse "Two-factor authentication is already enabled"
+// @Failure 429 {object} utils.RetryAfterErrorResponse "Too many wrong codes or passwords; retry after the Retry-After header"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/2fa/confirm [post]
+func (h *UserHandler) ConfirmTwoFactor(context *gin.Context) {
+	var request models.TwoFactorCodeRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+
+	user, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+	now := time.Now()
+	if rejectLockedOut(context, h.Lockouts, user.Email, now) {
+		return
+	}
+
+	codes, err := models.ConfirmTOTP(context.Request.Context(), h.TwoFactor, user.ID, request.Code)
+	switch {
+	case errors.Is(err, models.ErrTwoFactorNotEnabled):
+		context.Error(models.BadRequest("two_factor_setup_not_started", "Two-factor setup was not started"))
+		return
+	case errors.Is(err, models.ErrTwoFactorEnabled):
+		context.Error(models.Conflict("two_factor_already_enabled", "Two-factor authentication is already enabled"))
+		return
+	case errors.Is(err, models.ErrInvalidTwoFactorCode):
+		lockout.FailLogin(context.Request.Context(), h.Lockouts, user.Email, context.ClientIP(), now)
+		context.Error(models.Forbidden("invalid_two_factor_code", "Invalid two-factor code"))
+		return
+	case err != nil:
+		context.Error(fmt.Errorf("could not enable two-factor authentication: %w", err))
+		return
+	}
+	lockout.SucceedLogin(context.Request.Context(), h.Lockouts, user.Email)
+
+	context.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication after checking a code from the authenticator app or a recovery code.
+// @Tags Two-factor authentication
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param request body models.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} utils.MessageResponse "Two-factor authentication disabled"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request, or two-factor authentication is not enabled"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "The code is wrong"
+// @Failure 429 {object} utils.RetryAfterErrorResponse "Too many wrong codes or passwords; retry after the Retry-After header"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/2fa [delete]
+func (h *UserHandler) DisableTwoFactor(context *gin.Context) {
+	var request models.TwoFactorCodeRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+
+	user, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+	now := time.Now()
+	if rejectLockedOut(context, h.Lockouts, user.Email, now) {
+		return
+	}
+
+	err = models.VerifySecondFactor(context.Request.Context(), h.TwoFactor, user.ID, request.Code)
+	if errors.Is(err, models.ErrTwoFactorNotEnabled) {
+		context.Error(models.BadRequest("two_factor_not_enabled", "Two-factor authentication is not enabled"))
+		return
+	}
+	if errors.Is(err, models.ErrInvalidTwoFactorCode) {
+		lockout.FailLogin(context.Request.Context(), h.Lockouts, user.Email, context.ClientIP(), now)
+		context.Error(models.Forbidden("invalid_two_factor_code", "Invalid two-factor code"))
+		return
+	}
+	if err == nil {
+		lockout.SucceedLogin(context.Request.Context(), h.Lockouts, user.Email)
+		err = h.TwoFactor.Disable(context.Request.Context(), user.ID)
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not disable two-factor authentication: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token returned by /users/login and a code from the authenticator app, or a recovery code, for an access and refresh token.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} utils.LoginSuccessResponse "User logged in successfully with JWT token"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request"
+// @Failure 401 {object} utils.ErrorResponse "Invalid or expired challenge token, or invalid two-factor code"
+// @Failure 429 {object} utils.RetryAfterErrorResponse "Too many failed login attempts; retry after the Retry-After header"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/login/2fa [post]
+func (h *UserHandler) LoginTwoFactor(context *gin.Context) {
+	var request models.TwoFactorLoginRequest
+	err := context.ShouldBindJSON(&request)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+
+	userId, tokenVersion, err := helpers.VerifyChallengeToken(request.ChallengeToken)
+	if err != nil {
+		context.Error(models.Unauthorized("invalid_challenge_token", "Invalid or expired challenge token"))
+		return
+	}
+	user, err := h.Users.GetByID(context.Request.Context(), userId)
+	if err != nil || user.TokenVersion != tokenVersion {
+		context.Error(models.Unauthorized("invalid_challenge_token", "Invalid or expired challenge token"))
+		return
+	}
+
+	now := time.Now()
+	if rejectLockedOut(context, h.Lockouts, user.Email, now) {
+		return
+	}
+
+	err = models.VerifySecondFactor(context.Request.Context(), h.TwoFactor, user.ID, request.Code)
+	if errors.Is(err, models.ErrInvalidTwoFactorCode) || errors.Is(err, models.ErrTwoFactorNotEnabled) {
+		lockout.FailLogin(context.Request.Context(), h.Lockouts, user.Email, context.ClientIP(), now)
+		context.Error(models.Unauthorized("invalid_two_factor_code", "Invalid two-factor code"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not verify the code: %w", err))
+		return
+	}
+	lockout.SucceedLogin(context.Request.Context(), h.Lockouts, user.Email)
+
+	jwt, refreshToken, err := h.issueTokens(context, user)
+	if err != nil {
+		context.Error(fmt.Errorf("could not generate token: %w", err))
+		return
+	}
+	user.Password = ""
+	context.JSON(http.StatusOK, gin.H{"message": "User logged in successfully with JWT token", "jwt": jwt, "refresh_token": refreshToken, "user": user})
+}
+
+// rejectLockedOut turns the request away while the account is locked out, and reports whether it did.
+// Wrong two-factor codes count towards the same lockout as wrong passwords, so that a code cannot be
+// guessed, not even by someone holding a stolen access token.
+func rejectLockedOut(context *gin.Context, lockouts lockout.Store, email string, now time.Time) bool {
+	wait, err := lockout.Accounts(lockouts).RetryAfter(context.Request.Context(), lockout.AccountKey(email), now)
+	if err != nil {
+		context.Error(fmt.Errorf("could not check login attempts: %w", err))
+		return true
+	}
+	if wait > 0 {
+		middlewares.TooManyAttempts(context, wait)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactor(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/login", userHandler.Login)
+	router.POST("/users/login/2fa", userHandler.LoginTwoFactor)
+	router.POST("/users/me/2fa/setup", middlewares.Authenticate, userHandler.SetupTwoFactor)
+	router.POST("/users/me/2fa/confirm", middlewares.Authenticate, userHandler.ConfirmTwoFactor)
+	router.DELETE("/users/me/2fa", middlewares.Authenticate, userHandler.DisableTwoFactor)
+	router.POST("/users/:id/unlock", middlewares.Authenticate, middlewares.RequirePermission(models.PermUnlockUsers), userHandler.UnlockUser)
+
+	db.TruncateUsersTable()
+
+	user, token := createTestUser(t, "twofactor@gmail.com", "+250781475200")
+	admin, adminToken := createTestUser(t, "twofactor-admin@gmail.com", "+250781475201", models.RoleAdmin)
+	unlockPath := "/users/" + strconv.FormatInt(user.ID, 10) + "/unlock"
+
+	decode := func(t *testing.T, resp *httptest.ResponseRecorder) map[string]interface{} {
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		return body
+	}
+	totp := func(t *testing.T, secret string, step int64) string {
+		code, err := helpers.TOTPCode(secret, step)
+		assert.NoError(t, err)
+		return code
+	}
+	enrol := func(t *testing.T, token string) (string, []string) {
+		resp := testRequest(router, http.MethodPost, "/users/me/2fa/setup", token, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		body := decode(t, resp)
+		secret, _ := body["secret"].(string)
+		assert.Contains(t, body["otpauth_uri"], "otpauth://totp/")
+
+		code := totp(t, secret, helpers.TOTPStep(time.Now()))
+		resp = testRequest(router, http.MethodPost, "/users/me/2fa/confirm", token, `{"code": "`+code+`"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var confirmed struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &confirmed))
+		return secret, confirmed.RecoveryCodes
+	}
+	login := func(t *testing.T, email string) string {
+		resp := testRequest(router, http.MethodPost, "/users/login", "", `{"email": "`+email+`", "password": "1234"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		body := decode(t, resp)
+		assert.Equal(t, true, body["two_factor_required"])
+		assert.Nil(t, body["jwt"])
+		challenge, _ := body["challenge_token"].(string)
+		return challenge
+	}
+
+	t.Run("Confirming requires a started setup", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/me/2fa/confirm", token, `{"code": "123456"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Wrong confirmation code", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/me/2fa/setup", token, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = testRequest(router, http.MethodPost, "/users/me/2fa/confirm", token, `{"code": "000000x"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"code":"invalid_two_factor_code"`)
+	})
+
+	var secret string
+	var recoveryCodes []string
+	t.Run("Enrol", func(t *testing.T) {
+		secret, recoveryCodes = enrol(t, token)
+		assert.Len(t, recoveryCodes, 10)
+
+		resp := testRequest(router, http.MethodPost, "/users/me/2fa/setup", token, "")
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("Challenge token is not an access token", func(t *testing.T) {
+		challenge := login(t, user.Email)
+		resp := testRequest(router, http.MethodPost, "/users/me/2fa/setup", "Bearer "+challenge, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		challenge := login(t, user.Email)
+		resp := testRequest(router, http.MethodPost, "/users/login/2fa", "", `{"challenge_token": "`+challenge+`", "code": "000000x"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	var usedCode string
+	t.Run("Correct code completes the login", func(t *testing.T) {
+		challenge := login(t, user.Email)
+		usedCode = totp(t, secret, helpers.TOTPStep(time.Now())+1)
+		resp := testRequest(router, http.MethodPost, "/users/login/2fa", "", `{"challenge_token": "`+challenge+`", "code": "`+usedCode+`"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		body := decode(t, resp)
+		assert.NotEmpty(t, body["jwt"])
+		assert.NotEmpty(t, body["refresh_token"])
+	})
+
+	t.Run("Used code cannot be replayed", func(t *testing.T) {
+		challenge := login(t, user.Email)
+		resp := testRequest(router, http.MethodPost, "/users/login/2fa", "", `{"challenge_token": "`+challenge+`", "code": "`+usedCode+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Recovery code works once", func(t *testing.T) {
+		challenge := login(t, user.Email)
+		resp := testRequest(router, http.MethodPost, "/users/login/2fa", "", `{"challenge_token": "`+challenge+`", "code": "`+recoveryCodes[0]+`"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		challenge = login(t, user.Email)
+		resp = testRequest(router, http.MethodPost, "/users/login/2fa", "", `{"challenge_token": "`+challenge+`", "code": "`+recoveryCodes[0]+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		resp := testRequest(router, http.MethodDelete, "/users/me/2fa", token, `{"code": "000000x"}`)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+
+		resp = testRequest(router, http.MethodDelete, "/users/me/2fa", token, `{"code": "`+recoveryCodes[1]+`"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = testRequest(router, http.MethodPost, "/users/login", "", `{"email": "`+user.Email+`", "password": "1234"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NotEmpty(t, decode(t, resp)["jwt"])
+	})
+
+	t.Run("Admins must enrol when required", func(t *testing.T) {
+		t.Setenv("REQUIRE_ADMIN_2FA", "true")
+
+		resp := testRequest(router, http.MethodPost, unlockPath, adminToken, "")
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Two-factor authentication is required")
+
+		enrol(t, adminToken)
+		resp = testRequest(router, http.MethodPost, unlockPath, adminToken, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		enabled, err := userHandler.TwoFactor.Enabled(context.Background(), admin.ID)
+		assert.NoError(t, err)
+		assert.True(t, enabled)
+	})
+
+	t.Run("Wrong codes lock out a stolen access token", func(t *testing.T) {
+		t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "2")
+
+		for i := 0; i < 2; i++ {
+			resp := testRequest(router, http.MethodDelete, "/users/me/2fa", adminToken, `{"code": "000000x"}`)
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+		}
+		resp := testRequest(router, http.MethodDelete, "/users/me/2fa", adminToken, `{"code": "000000x"}`)
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+
+		enabled, err := userHandler.TwoFactor.Enabled(context.Background(), admin.ID)
+		assert.NoError(t, err)
+		assert.True(t, enabled)
+	})
+}
@@ -1,45 +1,82 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/middlewares"
 	"github.com/ngirimana/AnnounceIT/models"
 )
 
+// UserHandler serves the account endpoints, and those through which admins manage users
+type UserHandler struct {
+	Users models.UserStore
+	// Announcements is needed to export the data of a user and to list the announcements of any user
+	Announcements  models.AnnouncementStore
+	Sessions       models.SessionStore
+	APIKeys        models.APIKeyStore
+	TwoFactor      models.TwoFactorStore
+	PasswordResets models.PasswordResetStore
+	Blacklist      models.BlacklistStore
+	// Flags is needed to export the data of a user and to list the flags filed by or against any user
+	Flags models.FlagStore
+	// Lockouts counts the failed logins and two-factor codes of each account and client IP
+	Lockouts lockout.Store
+}
+
 // SignUp godoc
 // @Summary Sign up a new user
-// @Description Create a new user in the system
+// @Description Create a new user in the system and email them a link to verify the address. Unverified users can log in but cannot create announcements.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body models.User true "User data"
 // @Success 201 {object} utils.UserSuccessResponse  "User created successfully"
-// @Failure 400 {object} utils.ErrorResponse "Bad Request"
+// @Failure 400 {object} utils.ErrorResponse "Bad Request - could not parse the request"
 // @Failure 409 {object} utils.ErrorResponse "Conflict - user already exists"
+// @Failure 422 {object} utils.ValidationErrorResponse "A field is missing or invalid, such as an email address, a phone number not in E.164 format or a password that is too weak"
 // @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
 // @Router /users/signup [post]
-func SignUp(context *gin.Context) {
+func (h *UserHandler) SignUp(context *gin.Context) {
 
 	var user models.User
 	err := context.ShouldBindJSON(&user)
-
+	if rejectInvalidFields(context, err, &user) {
+		return
+	}
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"error": "could not parse the request"})
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	if rejectWeakPassword(context, "password", user.Password) {
 		return
 	}
-	_, err = models.GetUser(user.Email)
+	_, err = h.Users.GetByEmail(context.Request.Context(), user.Email)
 
 	if err == nil {
-		context.JSON(http.StatusConflict, gin.H{"error": "Conflict - user already exists"})
+		context.Error(models.Conflict("user_exists", "Conflict - user already exists"))
+		return
+	}
+	err = user.Save(context.Request.Context(), h.Users)
+	if errors.Is(err, models.ErrPhoneNumberTaken) || errors.Is(err, models.ErrEmailTaken) {
+		context.Error(models.Conflict("user_exists", "Conflict - user already exists"))
 		return
 	}
-	err = user.Save()
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		context.Error(err)
 		return
 	}
+	// A lost email can be sent again, so a failure to send it must not fail the signup
+	if err := sendVerification(&user); err != nil {
+		log.Printf("Could not verify the email address of user %d: %v", user.ID, err)
+	}
+
 	user.Password = ""
 	context.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "user": user})
 
@@ -52,52 +89,152 @@ func SignUp(context *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param user body utils.LoginData true "User login credentials"
-// @Success 200 {object} utils.LoginSuccessResponse "User logged in successfully with JWT token"
+// @Success 200 {object} utils.LoginSuccessResponse "User logged in successfully with JWT token, or utils.TwoFactorChallengeResponse when a two-factor code is required"
 // @Failure 400 {object} utils.ErrorResponse "Bad Request - could not parse the request or generate token"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized - invalid credentials"
+// @Failure 403 {object} utils.ErrorResponse "Account suspended"
+// @Failure 429 {object} utils.RetryAfterErrorResponse "Too many failed login attempts; retry after the Retry-After header"
 // @Failure 500 {object} utils.ErrorResponse "Internal Server Error - server error"
 // @Router /users/login [post]
-func Login(context *gin.Context) {
-	var user models.User
-	err := context.ShouldBindJSON(&user)
+func (h *UserHandler) Login(context *gin.Context) {
+	var request models.LoginRequest
+	err := context.ShouldBindJSON(&request)
+
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	user := models.User{Email: request.Email, Password: request.Password}
+
+	// Locked out callers are turned away before the password is hashed, which is what makes guessing expensive
+	now := time.Now()
+	ip := context.ClientIP()
+	wait, err := lockout.LoginRetryAfter(context.Request.Context(), h.Lockouts, user.Email, ip, now)
+	if err != nil {
+		context.Error(fmt.Errorf("could not check login attempts: %w", err))
+		return
+	}
+	if wait > 0 {
+		middlewares.TooManyAttempts(context, wait)
+		return
+	}
 
+	err = user.Authenticate(context.Request.Context(), h.Users)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"error": "could not parse the request"})
+		lockout.FailLogin(context.Request.Context(), h.Lockouts, user.Email, ip, now)
+		context.Error(models.Unauthorized("invalid_credentials", "Invalid credentials"))
+		return
+	}
+	if user.SuspendedAt != nil {
+		context.Error(models.Forbidden("account_suspended", "Account suspended"))
 		return
 	}
 
-	err = user.Authenticate()
+	// With two-factor authentication the password only earns a challenge token, exchanged at /users/login/2fa
+	twoFactor, err := h.TwoFactor.Enabled(context.Request.Context(), user.ID)
 	if err != nil {
-		context.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		context.Error(fmt.Errorf("could not check two-factor authentication: %w", err))
 		return
 	}
-	jwt, err := helpers.GenerateToken(user.Email, user.ID)
+	if twoFactor {
+		challenge, err := helpers.GenerateChallengeToken(user.ID, user.TokenVersion)
+		if err != nil {
+			context.Error(fmt.Errorf("could not generate token: %w", err))
+			return
+		}
+		context.JSON(http.StatusOK, gin.H{"message": "Two-factor code required", "two_factor_required": true, "challenge_token": challenge})
+		return
+	}
+
+	lockout.SucceedLogin(context.Request.Context(), h.Lockouts, user.Email)
+
+	jwt, refreshToken, err := h.issueTokens(context, &user)
 	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"error": "could not generate token"})
+		context.Error(fmt.Errorf("could not generate token: %w", err))
 		return
 	}
 	user.Password = ""
-	context.JSON(http.StatusOK, gin.H{"message": "User logged in successfully with JWT token", "jwt": jwt, "user": user})
+	context.JSON(http.StatusOK, gin.H{"message": "User logged in successfully with JWT token", "jwt": jwt, "refresh_token": refreshToken, "user": user})
 }
 
 // GetUser godoc
 // @Summary Retrieve user by email
-// @Description Get a user by their email address
+// @Description Get a user by their email address. Users can only retrieve themselves unless they have the users:read permission.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param email path string true "Email"
 // @Param Authorization header string true "Bearer token"
 // @Success 200 {object} utils.UserSuccessResponse "User retrieved successfully"
+// @Failure 403 {object} utils.ErrorResponse "You can only view your own profile"
 // @Failure 404 {object} utils.ErrorResponse "user not found"
 // @Router /users/{email} [get]
-func GetUser(context *gin.Context) {
+func (h *UserHandler) GetUser(context *gin.Context) {
 	email := context.Param("email")
-	user, err := models.GetUser(email)
+
+	// Checked before the lookup, so that the answer does not reveal whether the address is registered
+	if !callerCan(context, models.PermReadUsers) {
+		self, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+		if err != nil || self.Email != email {
+			context.Error(models.Forbidden("not_own_profile", "You can only view your own profile"))
+			return
+		}
+	}
+
+	user, err := h.Users.GetByEmail(context.Request.Context(), email)
 	if err != nil {
-		context.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		context.Error(models.NotFound("user_not_found", "user not found"))
 		return
 	}
 	user.Password = ""
 	context.JSON(http.StatusOK, gin.H{"message": "User retrieved successfully", "user": user})
 }
+
+// UpdateProfile godoc
+// @Summary Update my profile
+// @Description Change the names, phone number or address of the authenticated user. Fields that are not sent are left unchanged.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param profile body models.UserProfileUpdate true "Fields to update"
+// @Success 200 {object} utils.UserSuccessResponse "Profile updated successfully"
+// @Failure 400 {object} utils.ErrorResponse "could not parse the request"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 409 {object} utils.ErrorResponse "Phone number already in use"
+// @Failure 422 {object} utils.ValidationErrorResponse "A field is blank or too long, or the phone number is not in E.164 format"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me [patch]
+func (h *UserHandler) UpdateProfile(context *gin.Context) {
+	var update models.UserProfileUpdate
+	err := context.ShouldBindJSON(&update)
+	if rejectInvalidFields(context, err, &update) {
+		return
+	}
+	if err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	user, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return
+	}
+
+	err = user.UpdateProfile(context.Request.Context(), h.Users, update)
+	if errors.Is(err, models.ErrPhoneNumberTaken) {
+		context.Error(models.Conflict("phone_number_taken", "Phone number already in use"))
+		return
+	}
+	if err != nil {
+		context.Error(fmt.Errorf("could not update the profile: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully", "user": user})
+}
+
+// callerCan reports whether the authenticated caller, if any, has the permission
+func callerCan(context *gin.Context, permission models.Permission) bool {
+	return middlewares.CallerCan(context, permission)
+}
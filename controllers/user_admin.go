@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/ngirimana/AnnounceIT/notifications"
+)
+
+// ListUsers godoc
+// @Summary List users
+// @Description List users, oldest first, one page at a time, optionally searched and filtered. Pass the returned next_cursor to fetch the following page. Requires the users:read permission.
+// @Tags User administration
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param search query string false "Part of the email, first or last name, or phone number"
+// @Param role query string false "Only users with this role" Enums(advertiser, moderator, admin, auditor)
+// @Param suspended query bool false "Only suspended, or only active, users"
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} utils.UserListResponse "Users retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid query parameter"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch users"
+// @Router /admin/users [get]
+func (h *UserHandler) ListUsers(context *gin.Context) {
+	filter := models.UserFilter{
+		Search: context.Query("search"),
+		Role:   models.Role(context.Query("role")),
+		Cursor: context.Query("cursor"),
+	}
+	if filter.Role != "" && !filter.Role.IsValid() {
+		context.Error(models.BadRequest("invalid_query", "invalid role"))
+		return
+	}
+	if value := context.Query("suspended"); value != "" {
+		suspended, err := strconv.ParseBool(value)
+		if err != nil {
+			context.Error(models.BadRequest("invalid_query", "invalid suspended"))
+			return
+		}
+		filter.Suspended = &suspended
+	}
+	if value := context.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			context.Error(models.BadRequest("invalid_query", "invalid limit"))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	users, nextCursor, err := h.Users.List(context.Request.Context(), filter)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch users: %w", err))
+		return
+	}
+
+	response := gin.H{"users": users, "next_cursor": nil, "message": "Users retrieved successfully"}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+// userFromPath loads the user named by the id path parameter, answering 400 or 404 when there is none
+func (h *UserHandler) userFromPath(context *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		context.Error(models.BadRequest("invalid_user_id", "Invalid user ID"))
+		return nil, false
+	}
+	user, err := h.Users.GetByID(context.Request.Context(), id)
+	if err != nil {
+		context.Error(models.NotFound("user_not_found", "user not found"))
+		return nil, false
+	}
+	return user, true
+}
+
+// GetUserByID godoc
+// @Summary Get a user
+// @Description Get any user by ID, including their roles and suspension. Requires the users:read permission.
+// @Tags User administration
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.UserSuccessResponse "User retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Router /admin/users/{id} [get]
+func (h *UserHandler) GetUserByID(context *gin.Context) {
+	user, ok := h.userFromPath(context)
+	if !ok {
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "User retrieved successfully", "user": user})
+}
+
+// GetUserAnnouncements godoc
+// @Summary Get the announcements of a user
+// @Description Retrieve the announcements of any user, in every status, one page at a time. Requires the users:read permission.
+// @Tags User administration
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param status query string false "Only announcements with this status" Enums(pending, accepted, declined, active, deactivated)
+// @Param sort query string false "Sort column" Enums(id, start_date, end_date, create_date) default(create_date)
+// @Param order query string false "Sort order" Enums(asc, desc) default(desc)
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} utils.AnnouncementListResponse "Announcements retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID or query parameter"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch announcements"
+// @Router /admin/users/{id}/announcements [get]
+func (h *UserHandler) GetUserAnnouncements(context *gin.Context) {
+	user, ok := h.userFromPath(context)
+	if !ok {
+		return
+	}
+	filter, err := parseAnnouncementFilter(context)
+	if err != nil {
+		context.Error(err)
+		return
+	}
+
+	filter.OwnerID = &user.ID
+	listAnnouncements(context, h.Announcements, filter)
+}
+
+// GetUserFlags godoc
+// @Summary Get the flag history of a user
+// @Description List the flags a user filed and the flags filed on their announcements, newest first. Requires the users:read permission.
+// @Tags User administration
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.UserFlagsResponse "Flags retrieved successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 500 {object} utils.ErrorResponse "Could not fetch flags"
+// @Router /admin/users/{id}/flags [get]
+func (h *UserHandler) GetUserFlags(context *gin.Context) {
+	user, ok := h.userFromPath(context)
+	if !ok {
+		return
+	}
+
+	filed, err := h.Flags.ListByUser(context.Request.Context(), user.ID)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch flags: %w", err))
+		return
+	}
+	received, err := h.Flags.ListAgainstUser(context.Request.Context(), user.ID)
+	if err != nil {
+		context.Error(fmt.Errorf("could not fetch flags: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Flags retrieved successfully", "filed": filed, "received": received})
+}
+
+// SuspendUser godoc
+// @Summary Suspend a user
+// @Description Lock a user out of their account: they cannot log in, and every token, session and API key they have stops working. Requires the users:manage permission.
+// @Tags User administration
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Param request body models.SuspendRequest true "Reason for the suspension"
+// @Success 200 {object} utils.MessageResponse "User suspended successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID, could not parse the request, or suspending yourself"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 409 {object} utils.ErrorResponse "User is already suspended"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /admin/users/{id}/suspend [post]
+func (h *UserHandler) SuspendUser(context *gin.Context) {
+	user, ok := h.userFromPath(context)
+	if !ok {
+		return
+	}
+	var request models.SuspendRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		context.Error(models.BadRequest("invalid_request", "could not parse the request"))
+		return
+	}
+	// Otherwise the last admin could lock everyone out
+	if user.ID == context.GetInt64("userId") {
+		context.Error(models.BadRequest("cannot_suspend_self", "You cannot suspend yourself"))
+		return
+	}
+
+	err := h.Users.Suspend(context.Request.Context(), user.ID, request.Reason)
+	if err != nil {
+		context.Error(fmt.Errorf("could not suspend the user: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "User suspended successfully"})
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate a user
+// @Description Lift the suspension of a user, who can then log in again. Requires the users:manage permission.
+// @Tags User administration
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.MessageResponse "User reactivated successfully"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 409 {object} utils.ErrorResponse "User is not suspended"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /admin/users/{id}/reactivate [post]
+func (h *UserHandler) ReactivateUser(context *gin.Context) {
+	user, ok := h.userFromPath(context)
+	if !ok {
+		return
+	}
+
+	err := h.Users.Reactivate(context.Request.Context(), user.ID)
+	if err != nil {
+		context.Error(fmt.Errorf("could not reactivate the user: %w", err))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "User reactivated successfully"})
+}
+
+// ForcePasswordReset godoc
+// @Summary Force a password reset
+// @Description Make the password of a user stop working, log them out everywhere and send them a password reset token, for instance when the password may have leaked. Requires the users:manage permission.
+// @Tags User administration
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Param id path int true "User ID"
+// @Success 200 {object} utils.MessageResponse "Password reset forced"
+// @Failure 400 {object} utils.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 403 {object} utils.ErrorResponse "Insufficient permissions"
+// @Failure 404 {object} utils.ErrorResponse "user not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /admin/users/{id}/password-reset [post]
+func (h *UserHandler) ForcePasswordReset(context *gin.Context) {
+	user, ok := h.userFromPath(context)
+	if !ok {
+		return
+	}
+
+	err := h.Users.ForcePasswordReset(context.Request.Context(), user.ID)
+	if err != nil {
+		context.Error(fmt.Errorf("could not reset the password: %w", err))
+		return
+	}
+
+	// The password is gone either way, so a failure to send the token is only logged; the user can ask for another
+	ttl := config.Duration("PASSWORD_RESET_TTL", time.Hour)
+	token, err := models.CreatePasswordReset(context.Request.Context(), h.PasswordResets, user.ID, ttl)
+	if err != nil {
+		log.Printf("Could not create password reset for user %d: %v", user.ID, err)
+	} else if err = notifications.Default.PasswordReset(user.Email, token); err != nil {
+		log.Printf("Could not send password reset to user %d: %v", user.ID, err)
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Password reset forced; a reset token has been sent to the user"})
+}
@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/ngirimana/AnnounceIT/notifications"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAdministration(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/login", userHandler.Login)
+	router.GET("/users/me/announcements", middlewares.Authenticate, announcementHandler.GetMyAnnouncements)
+	router.GET("/users/:email", middlewares.Authenticate, userHandler.GetUser)
+	read := middlewares.RequirePermission(models.PermReadUsers)
+	manage := middlewares.RequirePermission(models.PermManageUsers)
+	router.GET("/admin/users", middlewares.Authenticate, read, userHandler.ListUsers)
+	router.GET("/admin/users/:id", middlewares.Authenticate, read, userHandler.GetUserByID)
+	router.GET("/admin/users/:id/announcements", middlewares.Authenticate, read, userHandler.GetUserAnnouncements)
+	router.GET("/admin/users/:id/flags", middlewares.Authenticate, read, userHandler.GetUserFlags)
+	router.POST("/admin/users/:id/suspend", middlewares.Authenticate, manage, userHandler.SuspendUser)
+	router.POST("/admin/users/:id/reactivate", middlewares.Authenticate, manage, userHandler.ReactivateUser)
+	router.POST("/admin/users/:id/password-reset", middlewares.Authenticate, manage, userHandler.ForcePasswordReset)
+
+	notifier := &recordingNotifier{resets: map[string]string{}}
+	previous := notifications.Default
+	notifications.Default = notifier
+	defer func() { notifications.Default = previous }()
+
+	db.TruncateUsersTable()
+	admin, adminToken := createTestUser(t, "admin-users@gmail.com", "+250781475500", models.RoleAdmin)
+	alice, aliceToken := createTestUser(t, "alice@gmail.com", "+250781475501")
+	bob, bobToken := createTestUser(t, "bob@gmail.com", "+250781475502", models.RoleModerator)
+	alicePath := "/admin/users/" + strconv.FormatInt(alice.ID, 10)
+
+	as := func(token string) map[string]string {
+		return map[string]string{"Authorization": token}
+	}
+	login := func(email, password string) *httptest.ResponseRecorder {
+		return sendTestRequest(router, http.MethodPost, "/users/login", `{"email": "`+email+`", "password": "`+password+`"}`, nil)
+	}
+	type userList struct {
+		Users      []models.User `json:"users"`
+		NextCursor *string       `json:"next_cursor"`
+	}
+	listUsers := func(query string) userList {
+		resp := sendTestRequest(router, http.MethodGet, "/admin/users"+query, "", as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body userList
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		return body
+	}
+
+	t.Run("Users can only look up themselves", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, "/users/alice@gmail.com", "", as(aliceToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodGet, "/users/bob@gmail.com", "", as(aliceToken))
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.NotContains(t, resp.Body.String(), "+250781475502")
+
+		resp = sendTestRequest(router, http.MethodGet, "/users/bob@gmail.com", "", as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), "+250781475502")
+	})
+
+	t.Run("Only admins can manage users", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, "/admin/users", "", as(aliceToken))
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodGet, alicePath, "", as(bobToken))
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodPost, alicePath+"/suspend", `{"reason": "Spam"}`, as(bobToken))
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("List users page by page", func(t *testing.T) {
+		first := listUsers("?limit=2")
+		if assert.Len(t, first.Users, 2) && assert.NotNil(t, first.NextCursor) {
+			assert.Equal(t, admin.ID, first.Users[0].ID)
+			assert.Equal(t, alice.ID, first.Users[1].ID)
+			assert.Empty(t, first.Users[0].Password)
+
+			second := listUsers("?limit=2&cursor=" + *first.NextCursor)
+			if assert.Len(t, second.Users, 1) {
+				assert.Equal(t, bob.ID, second.Users[0].ID)
+			}
+			assert.Nil(t, second.NextCursor)
+		}
+	})
+
+	t.Run("Search and filter users", func(t *testing.T) {
+		found := listUsers("?search=ALICE")
+		if assert.Len(t, found.Users, 1) {
+			assert.Equal(t, alice.ID, found.Users[0].ID)
+		}
+
+		found = listUsers("?search=%25")
+		assert.Empty(t, found.Users, "LIKE wildcards match literally")
+
+		found = listUsers("?role=moderator")
+		if assert.Len(t, found.Users, 1) {
+			assert.Equal(t, bob.ID, found.Users[0].ID)
+		}
+
+		for _, query := range []string{"?role=owner", "?suspended=maybe", "?limit=0", "?cursor=garbage"} {
+			resp := sendTestRequest(router, http.MethodGet, "/admin/users"+query, "", as(adminToken))
+			assert.Equal(t, http.StatusBadRequest, resp.Code, query)
+		}
+	})
+
+	t.Run("View a user", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, alicePath, "", as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), "alice@gmail.com")
+
+		resp = sendTestRequest(router, http.MethodGet, "/admin/users/999999", "", as(adminToken))
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodGet, "/admin/users/abc", "", as(adminToken))
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("View the announcements and flags of a user", func(t *testing.T) {
+		pending := createTestAnnouncement(t, alice.ID, models.Pending)
+		createTestAnnouncement(t, bob.ID, models.Active)
+		flag := models.Flag{AnnouncementID: pending.ID, UserID: bob.ID, Reason: models.FlagSpam}
+		assert.NoError(t, flag.Create(context.Background(), userHandler.Flags))
+
+		resp := sendTestRequest(router, http.MethodGet, alicePath+"/announcements", "", as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var announcements struct {
+			Announcements []models.Announcement `json:"announcements"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &announcements))
+		if assert.Len(t, announcements.Announcements, 1) {
+			assert.Equal(t, pending.ID, announcements.Announcements[0].ID)
+		}
+
+		resp = sendTestRequest(router, http.MethodGet, alicePath+"/flags", "", as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var flags struct {
+			Filed    []models.Flag `json:"filed"`
+			Received []models.Flag `json:"received"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &flags))
+		assert.Empty(t, flags.Filed)
+		if assert.Len(t, flags.Received, 1) {
+			assert.Equal(t, flag.ID, flags.Received[0].ID)
+		}
+
+		resp = sendTestRequest(router, http.MethodGet, "/admin/users/"+strconv.FormatInt(bob.ID, 10)+"/flags", "", as(adminToken))
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &flags))
+		assert.Len(t, flags.Filed, 1)
+		assert.Empty(t, flags.Received)
+	})
+
+	t.Run("Suspend and reactivate a user", func(t *testing.T) {
+		_, apiKey, err := models.CreateAPIKey(context.Background(), userHandler.APIKeys, alice.ID, "Script", []models.Permission{models.PermReadAnnouncements}, nil)
+		assert.NoError(t, err)
+
+		resp := sendTestRequest(router, http.MethodPost, alicePath+"/suspend", `{}`, as(adminToken))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "a reason is required")
+
+		resp = sendTestRequest(router, http.MethodPost, "/admin/users/"+strconv.FormatInt(admin.ID, 10)+"/suspend", `{"reason": "Oops"}`, as(adminToken))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "admins cannot suspend themselves")
+
+		resp = sendTestRequest(router, http.MethodPost, alicePath+"/suspend", `{"reason": "Spam"}`, as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = sendTestRequest(router, http.MethodPost, alicePath+"/suspend", `{"reason": "Spam"}`, as(adminToken))
+		assert.Equal(t, http.StatusConflict, resp.Code)
+
+		resp = sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", as(aliceToken))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, "existing tokens are revoked")
+		resp = sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", map[string]string{"X-API-Key": apiKey})
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		resp = login("alice@gmail.com", "1234")
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Account suspended")
+
+		suspended := listUsers("?suspended=true")
+		if assert.Len(t, suspended.Users, 1) {
+			assert.Equal(t, alice.ID, suspended.Users[0].ID)
+			assert.NotNil(t, suspended.Users[0].SuspendedAt)
+			assert.Equal(t, "Spam", suspended.Users[0].SuspensionReason)
+		}
+		assert.Len(t, listUsers("?suspended=false").Users, 2)
+
+		resp = sendTestRequest(router, http.MethodPost, alicePath+"/reactivate", "", as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = sendTestRequest(router, http.MethodPost, alicePath+"/reactivate", "", as(adminToken))
+		assert.Equal(t, http.StatusConflict, resp.Code)
+
+		resp = login("alice@gmail.com", "1234")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", map[string]string{"X-API-Key": apiKey})
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Force a password reset", func(t *testing.T) {
+		bobPath := "/admin/users/" + strconv.FormatInt(bob.ID, 10)
+		resp := sendTestRequest(router, http.MethodPost, bobPath+"/password-reset", "", as(adminToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		resp = login("bob@gmail.com", "1234")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, "the old password no longer works")
+		resp = sendTestRequest(router, http.MethodGet, "/users/me/announcements", "", as(bobToken))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, "existing tokens are revoked")
+		assert.NotEmpty(t, notifier.resets["bob@gmail.com"], "a reset token is sent")
+	})
+}
+
+func TestUserAdministrationInMemory(t *testing.T) {
+	t.Parallel()
+	handler, users := memoryUserHandler()
+	router := gin.Default()
+	router.Use(middlewares.HandleErrors)
+	router.GET("/admin/users", fakeAuthenticate, handler.ListUsers)
+	router.POST("/admin/users/:id/suspend", fakeAuthenticate, handler.SuspendUser)
+	router.POST("/admin/users/:id/reactivate", fakeAuthenticate, handler.ReactivateUser)
+
+	for i, name := range []string{"carol", "dave", "erin"} {
+		user := models.User{Email: name + "@gmail.com", Password: "1234", FirstName: name, LastName: "User", PhoneNumber: "+25078147560" + strconv.Itoa(i), Address: "KG 23 ST"}
+		assert.NoError(t, user.Save(context.Background(), users), "Failed to create test user")
+	}
+	assert.NoError(t, users.GrantRole(context.Background(), 1, models.RoleAdmin, 0))
+	adminToken := map[string]string{"Authorization": fakeToken(1, models.RoleAdmin)}
+
+	t.Run("Suspend and reactivate", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodPost, "/admin/users/2/suspend", `{"reason": "Spam"}`, adminToken)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = sendTestRequest(router, http.MethodPost, "/admin/users/2/suspend", `{"reason": "Spam"}`, adminToken)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+
+		suspended, err := users.IsSuspended(context.Background(), 2)
+		assert.NoError(t, err)
+		assert.True(t, suspended)
+
+		resp = sendTestRequest(router, http.MethodGet, "/admin/users?suspended=true", "", adminToken)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), "dave@gmail.com")
+		assert.NotContains(t, resp.Body.String(), "carol@gmail.com")
+
+		resp = sendTestRequest(router, http.MethodPost, "/admin/users/2/reactivate", "", adminToken)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		resp = sendTestRequest(router, http.MethodPost, "/admin/users/2/reactivate", "", adminToken)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("List users page by page", func(t *testing.T) {
+		resp := sendTestRequest(router, http.MethodGet, "/admin/users?limit=2", "", adminToken)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var first struct {
+			Users      []models.User `json:"users"`
+			NextCursor *string       `json:"next_cursor"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &first))
+		if assert.Len(t, first.Users, 2) && assert.NotNil(t, first.NextCursor) {
+			assert.Equal(t, int64(1), first.Users[0].ID)
+			resp = sendTestRequest(router, http.MethodGet, "/admin/users?limit=2&cursor="+*first.NextCursor, "", adminToken)
+			assert.Contains(t, resp.Body.String(), "erin@gmail.com")
+			assert.Contains(t, resp.Body.String(), `"next_cursor":null`)
+		}
+
+		resp = sendTestRequest(router, http.MethodGet, "/admin/users?role=admin", "", adminToken)
+		assert.Contains(t, resp.Body.String(), "carol@gmail.com")
+		assert.NotContains(t, resp.Body.String(), "dave@gmail.com")
+	})
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+func init() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// Report fields by the names clients send them under
+	validate.RegisterTagNameFunc(jsonFieldName)
+
+	validate.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	validate.RegisterValidation("email_address", func(fl validator.FieldLevel) bool {
+		return models.ValidateEmail(fl.Field().String()) == nil
+	})
+	validate.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return models.ValidatePhoneNumber(fl.Field().String()) == nil
+	})
+	validate.RegisterValidation("announcement_length", func(fl validator.FieldLevel) bool {
+		return utf8.RuneCountInString(fl.Field().String()) <= maxAnnouncementLength()
+	})
+	// after=Field requires a time to be later than the sibling time Field
+	validate.RegisterValidation("after", func(fl validator.FieldLevel) bool {
+		other := fl.Parent().FieldByName(fl.Param())
+		this, ok := fl.Field().Interface().(time.Time)
+		if !ok || !other.IsValid() {
+			return false
+		}
+		before, ok := other.Interface().(time.Time)
+		return ok && this.After(before)
+	})
+}
+
+func maxAnnouncementLength() int {
+	return config.Int("ANNOUNCEMENT_MAX_LENGTH", 1000)
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// rejectInvalidFields fails the request with a validation error listing every field that failed, when err comes
+// from binding a body that parsed but did not validate, and reports whether it did. Other errors are left to the caller.
+func rejectInvalidFields(context *gin.Context, err error, obj any) bool {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+
+	fields := make([]models.FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, describeFieldError(fe, obj))
+	}
+	context.Error(models.Invalid(fields...))
+	return true
+}
+
+// invalidField fails the request for a single field rejected outside of binding validation
+func invalidField(context *gin.Context, field models.FieldError) {
+	context.Error(models.Invalid(field))
+}
+
+func describeFieldError(fe validator.FieldError, obj any) models.FieldError {
+	field := models.FieldError{Field: fe.Field()}
+	switch fe.Tag() {
+	case "required", "notblank":
+		field.Code, field.Message = "required", "is required"
+	case "email_address":
+		field.Code, field.Message = "invalid_email", "must be an email address such as jane@example.com"
+	case "phone":
+		field.Code, field.Message = "invalid_phone", "must be an E.164 phone number such as +250781234567"
+	case "max":
+		field.Code, field.Message = "too_long", "must be at most "+fe.Param()+" characters"
+	case "announcement_length":
+		field.Code, field.Message = "too_long", fmt.Sprintf("must be at most %d characters", maxAnnouncementLength())
+	case "after":
+		field.Code, field.Message = "invalid_date_range", "must be after "+siblingJSONName(obj, fe.Param())
+	default:
+		field.Code, field.Message = "invalid", "is invalid"
+	}
+	return field
+}
+
+// siblingJSONName returns the JSON name of a field of the struct obj points to, given its Go name
+func siblingJSONName(obj any, name string) string {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		if field, ok := t.FieldByName(name); ok {
+			return jsonFieldName(field)
+		}
+	}
+	return name
+}
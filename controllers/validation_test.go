@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidation(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/signup", userHandler.SignUp)
+	router.PATCH("/users/me", middlewares.Authenticate, userHandler.UpdateProfile)
+	router.POST("/announcements", middlewares.Authenticate, announcementHandler.CreateAnnouncement)
+
+	db.TruncateUsersTable()
+	_, token := createTestUser(t, "validation@gmail.com", "+250781475700")
+
+	// fieldCodes returns the code reported for every failing field of a 422 response
+	fieldCodes := func(t *testing.T, resp *httptest.ResponseRecorder) map[string]string {
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		var body struct {
+			Code   string              `json:"code"`
+			Fields []models.FieldError `json:"fields"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, "validation_failed", body.Code)
+		codes := map[string]string{}
+		for _, field := range body.Fields {
+			assert.NotEmpty(t, field.Message)
+			codes[field.Field] = field.Code
+		}
+		return codes
+	}
+
+	t.Run("Signup lists every invalid field", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/signup", token, `{
+			"email": "not-an-email",
+			"password": "correct-horse-42",
+			"first_name": " ",
+			"phone_number": "0781 475 701",
+			"address": "KG 23 ST"
+		}`)
+		assert.Equal(t, map[string]string{
+			"email":        "invalid_email",
+			"first_name":   "required",
+			"last_name":    "required",
+			"phone_number": "invalid_phone",
+		}, fieldCodes(t, resp))
+	})
+
+	t.Run("Malformed JSON is still a bad request", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/signup", token, `{"email": `)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Profile phone numbers must be E.164", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPatch, "/users/me", token, `{"phone_number": "+0781475701"}`)
+		assert.Equal(t, map[string]string{"phone_number": "invalid_phone"}, fieldCodes(t, resp))
+	})
+
+	t.Run("Announcements need text and ordered dates", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements", token, `{
+			"text": "",
+			"start_date": "2030-01-01T15:30:00Z",
+			"end_date": "2030-01-01T13:30:00Z"
+		}`)
+		assert.Equal(t, map[string]string{"text": "required", "end_date": "invalid_date_range"}, fieldCodes(t, resp))
+
+		resp = testRequest(router, http.MethodPost, "/announcements", token, `{"text": "No dates"}`)
+		assert.Equal(t, map[string]string{"start_date": "required", "end_date": "required"}, fieldCodes(t, resp))
+	})
+
+	t.Run("Announcement text has a maximum length", func(t *testing.T) {
+		t.Setenv("ANNOUNCEMENT_MAX_LENGTH", "10")
+		body := `{"text": "%s", "start_date": "2030-01-01T13:30:00Z", "end_date": "2030-01-01T15:30:00Z"}`
+
+		resp := testRequest(router, http.MethodPost, "/announcements", token, strings.Replace(body, "%s", "Eleven char", 1))
+		assert.Equal(t, map[string]string{"text": "too_long"}, fieldCodes(t, resp))
+		assert.Contains(t, resp.Body.String(), "at most 10 characters")
+
+		resp = testRequest(router, http.MethodPost, "/announcements", token, strings.Replace(body, "%s", "Ten chars!", 1))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/ngirimana/AnnounceIT/notifications"
+)
+
+// sendVerification emails the user a link to verify their email address
+func sendVerification(user *models.User) error {
+	token, err := helpers.GenerateVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return fmt.Errorf("could not create email verification: %w", err)
+	}
+	if err := notifications.Default.EmailVerification(user.Email, token); err != nil {
+		return fmt.Errorf("could not send email verification: %w", err)
+	}
+	return nil
+}
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Mark the email address of a user as verified with the token emailed at signup. This is the link in the email, so it needs no other credentials.
+// @Tags Users
+// @Produce json
+// @Param token query string true "Verification token from the email"
+// @Success 200 {object} utils.MessageResponse "Email address verified"
+// @Failure 400 {object} utils.ErrorResponse "Invalid or expired verification token"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/verify [get]
+func (h *UserHandler) VerifyEmail(context *gin.Context) {
+	userId, email, err := helpers.VerifyVerificationToken(context.Query("token"))
+	if err == nil {
+		err = h.Users.VerifyEmail(context.Request.Context(), userId, email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			context.Error(fmt.Errorf("could not verify the email address: %w", err))
+			return
+		}
+	}
+	if err != nil {
+		context.Error(models.BadRequest("invalid_verification_token", "Invalid or expired verification token"))
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// ResendVerification godoc
+// @Summary Resend the verification email
+// @Description Email the authenticated user a new link to verify their email address, for when the one sent at signup expired or got lost.
+// @Tags Users
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Success 200 {object} utils.MessageResponse "Verification email sent"
+// @Failure 401 {object} utils.ErrorResponse "Authorization token is required or invalid"
+// @Failure 409 {object} utils.ErrorResponse "Email address already verified"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
+// @Router /users/me/verification [post]
+func (h *UserHandler) ResendVerification(context *gin.Context) {
+	user, err := h.Users.GetByID(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not load the user: %w", err))
+		return
+	}
+	if user.VerifiedAt != nil {
+		context.Error(models.Conflict("email_already_verified", "Email address already verified"))
+		return
+	}
+
+	if err := sendVerification(user); err != nil {
+		context.Error(err)
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// rejectUnverified answers 403 when the caller has not verified their email address, and reports whether it did
+func rejectUnverified(context *gin.Context, users models.UserStore) bool {
+	verified, err := users.IsEmailVerified(context.Request.Context(), context.GetInt64("userId"))
+	if err != nil {
+		context.Error(fmt.Errorf("could not check the email address: %w", err))
+		return true
+	}
+	if !verified {
+		context.Error(models.Forbidden("email_not_verified", "Verify your email address before creating announcements"))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/ngirimana/AnnounceIT/db"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/notifications"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerification(t *testing.T) {
+	router := newTestRouter()
+	router.POST("/users/signup", userHandler.SignUp)
+	router.GET("/users/verify", userHandler.VerifyEmail)
+	router.POST("/users/me/verification", middlewares.Authenticate, userHandler.ResendVerification)
+	router.POST("/announcements", middlewares.Authenticate, announcementHandler.CreateAnnouncement)
+
+	// Messages go to a file, like MAIL_FILE does, so the test reads the link a user would click
+	mailFile := filepath.Join(t.TempDir(), "mail.txt")
+	previous := notifications.Default
+	notifications.Default = notifications.MailNotifier{Mailer: notifications.FileMailer{Path: mailFile}, BaseURL: "http://localhost:8000"}
+	defer func() { notifications.Default = previous }()
+
+	db.TruncateUsersTable()
+
+	announcementBody := `{
+		"end_date": "2030-01-01T15:30:00.000Z",
+		"start_date": "2030-01-01T13:30:00.000Z",
+		"text": "Verified announcement"
+	}`
+
+	t.Run("Invalid email addresses are refused", func(t *testing.T) {
+		for _, email := range []string{"not-an-email", "Jane <jane@gmail.com>", "jane@"} {
+			body := `{"email": "` + email + `", "password": "correct-horse-42", "first_name": "Jane", "last_name": "Doe", "phone_number": "+250781475300", "address": "KG 23 ST"}`
+			resp := testRequest(router, http.MethodPost, "/users/signup", "", body)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, email)
+			assert.Contains(t, resp.Body.String(), `"code":"invalid_email"`, email)
+		}
+	})
+
+	var link string
+	t.Run("Signup sends a verification link", func(t *testing.T) {
+		body := `{"email": "verify@gmail.com", "password": "correct-horse-42", "first_name": "Jane", "last_name": "Doe", "phone_number": "+250781475301", "address": "KG 23 ST", "verified_at": "2020-01-01T00:00:00Z"}`
+		resp := testRequest(router, http.MethodPost, "/users/signup", "", body)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"verified_at":null`)
+
+		mail, err := os.ReadFile(mailFile)
+		assert.NoError(t, err)
+		assert.Contains(t, string(mail), "To: verify@gmail.com")
+		link = regexp.MustCompile(`http://localhost:8000(/users/verify\?token=\S+)`).FindStringSubmatch(string(mail))[1]
+	})
+
+	user, err := sqlUsers.GetByEmail(context.Background(), "verify@gmail.com")
+	assert.NoError(t, err)
+	token := sessionToken(t, user)
+
+	t.Run("Unverified users cannot create announcements", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements", token, announcementBody)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Verify your email address")
+	})
+
+	t.Run("Resend the verification email", func(t *testing.T) {
+		assert.NoError(t, os.Remove(mailFile))
+		resp := testRequest(router, http.MethodPost, "/users/me/verification", token, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		mail, err := os.ReadFile(mailFile)
+		assert.NoError(t, err)
+		assert.Contains(t, string(mail), "To: verify@gmail.com")
+		assert.Contains(t, string(mail), "http://localhost:8000/users/verify?token=")
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, "/users/verify?token=nope", "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+		// An access token is signed with the same keys but must not verify an address
+		resp = testRequest(router, http.MethodGet, "/users/verify?token="+url.QueryEscape(token), "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		resp := testRequest(router, http.MethodGet, link, "", "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		verified, err := sqlUsers.GetByEmail(context.Background(), "verify@gmail.com")
+		assert.NoError(t, err)
+		assert.NotNil(t, verified.VerifiedAt)
+	})
+
+	t.Run("Verified users can create announcements", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/announcements", token, announcementBody)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+
+	t.Run("Verified addresses get no further email", func(t *testing.T) {
+		resp := testRequest(router, http.MethodPost, "/users/me/verification", token, "")
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"code":"email_already_verified"`)
+	})
+}
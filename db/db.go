@@ -2,7 +2,10 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -33,7 +36,12 @@ func createTables() {
 		first_name TEXT NOT NULL,
 		phone_number TEXT NOT NULL UNIQUE,
 		address TEXT NOT NULL,
-		is_admin BOOLEAN NOT NULL DEFAULT FALSE
+		token_version INTEGER NOT NULL DEFAULT 0,
+		verified_at DATETIME,
+		suspended_at DATETIME,
+		suspension_reason TEXT NOT NULL DEFAULT '',
+		deletion_scheduled_at DATETIME,
+		deleted_at DATETIME
 	);`
 
 	_, err := DB.Exec(createUsersTable)
@@ -41,15 +49,42 @@ func createTables() {
 		panic("Could not create users table: " + err.Error())
 	}
 
+	addColumnIfMissing("users", "token_version", "INTEGER NOT NULL DEFAULT 0")
+	migrateVerifiedAt()
+	addColumnIfMissing("users", "suspended_at", "DATETIME")
+	addColumnIfMissing("users", "suspension_reason", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfMissing("users", "deletion_scheduled_at", "DATETIME")
+	addColumnIfMissing("users", "deleted_at", "DATETIME")
+
+	createUserRolesTable := `
+	CREATE TABLE IF NOT EXISTS user_roles (
+		user_id INTEGER NOT NULL,
+		role TEXT NOT NULL,
+		granted_by INTEGER,
+		granted_at DATETIME NOT NULL,
+		PRIMARY KEY(user_id, role),
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createUserRolesTable)
+	if err != nil {
+		panic("Could not create user_roles table: " + err.Error())
+	}
+
+	migrateSystemActor("user_roles", "granted_by", createUserRolesTable)
+
+	migrateIsAdminToRoles()
+
 	createAnnouncementsTable := `
 	CREATE TABLE IF NOT EXISTS announcements (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		owner_id INTEGER NOT NULL,
-		status  string NOT NULL DEFAULT 'pending',
+		status TEXT NOT NULL DEFAULT 'pending',
 		text TEXT NOT NULL ,
 		start_date DATETIME NOT NULL,
 		end_date DATETIME NOT NULL,
 		create_date DATETIME NOT NULL,
+		deleted_at DATETIME,
 		FOREIGN KEY(owner_id) REFERENCES users(id)
 	);`
 
@@ -58,16 +93,399 @@ func createTables() {
 		panic("Could not create announcements table: " + err.Error())
 	}
 
+	addColumnIfMissing("announcements", "deleted_at", "DATETIME")
+	migrateAnnouncementDatesToUTC()
+
+	createStatusChangesTable := `
+	CREATE TABLE IF NOT EXISTS status_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		announcement_id INTEGER NOT NULL,
+		from_status TEXT NOT NULL,
+		to_status TEXT NOT NULL,
+		changed_by INTEGER,
+		reason TEXT NOT NULL DEFAULT '',
+		changed_at DATETIME NOT NULL,
+		FOREIGN KEY(announcement_id) REFERENCES announcements(id),
+		FOREIGN KEY(changed_by) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createStatusChangesTable)
+	if err != nil {
+		panic("Could not create status_changes table: " + err.Error())
+	}
+
+	migrateSystemActor("status_changes", "changed_by", createStatusChangesTable)
+
+	migrateStatusNames("announcements", "status")
+	migrateStatusNames("status_changes", "from_status")
+	migrateStatusNames("status_changes", "to_status")
+
+	createPasswordResetsTable := `
+	CREATE TABLE IF NOT EXISTS password_resets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createPasswordResetsTable)
+	if err != nil {
+		panic("Could not create password_resets table: " + err.Error())
+	}
+
+	createFlagsTable := `
+	CREATE TABLE IF NOT EXISTS flags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		announcement_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		reason TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'open',
+		created_on DATETIME NOT NULL,
+		resolved_by INTEGER,
+		resolved_at DATETIME,
+		UNIQUE(announcement_id, user_id),
+		FOREIGN KEY(announcement_id) REFERENCES announcements(id),
+		FOREIGN KEY(user_id) REFERENCES users(id),
+		FOREIGN KEY(resolved_by) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createFlagsTable)
+	if err != nil {
+		panic("Could not create flags table: " + err.Error())
+	}
+
+	createBlacklistChangesTable := `
+	CREATE TABLE IF NOT EXISTS blacklist_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		expires_at DATETIME,
+		changed_by INTEGER NOT NULL,
+		changed_at DATETIME NOT NULL,
+		FOREIGN KEY(user_id) REFERENCES users(id),
+		FOREIGN KEY(changed_by) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createBlacklistChangesTable)
+	if err != nil {
+		panic("Could not create blacklist_changes table: " + err.Error())
+	}
+
+	createSessionsTable := `
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		ip_address TEXT NOT NULL,
+		user_agent TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		last_seen_at DATETIME NOT NULL,
+		revoked_at DATETIME,
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createSessionsTable)
+	if err != nil {
+		panic("Could not create sessions table: " + err.Error())
+	}
+
+	createRefreshTokensTable := `
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		session_id INTEGER,
+		family_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		token_version INTEGER NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		used_at DATETIME,
+		revoked_at DATETIME,
+		FOREIGN KEY(user_id) REFERENCES users(id),
+		FOREIGN KEY(session_id) REFERENCES sessions(id)
+	);`
+
+	_, err = DB.Exec(createRefreshTokensTable)
+	if err != nil {
+		panic("Could not create refresh_tokens table: " + err.Error())
+	}
+
+	addColumnIfMissing("refresh_tokens", "session_id", "INTEGER REFERENCES sessions(id)")
+
+	createRevokedTokensTable := `
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		token_id TEXT PRIMARY KEY,
+		expires_at DATETIME NOT NULL
+	);`
+
+	_, err = DB.Exec(createRevokedTokensTable)
+	if err != nil {
+		panic("Could not create revoked_tokens table: " + err.Error())
+	}
+
+	createAPIKeysTable := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL UNIQUE,
+		key_hash TEXT NOT NULL,
+		scopes TEXT NOT NULL,
+		expires_at DATETIME,
+		last_used_at DATETIME,
+		created_at DATETIME NOT NULL,
+		revoked_at DATETIME,
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createAPIKeysTable)
+	if err != nil {
+		panic("Could not create api_keys table: " + err.Error())
+	}
+
+	createLoginAttemptsTable := `
+	CREATE TABLE IF NOT EXISTS login_attempts (
+		key TEXT PRIMARY KEY,
+		failures INTEGER NOT NULL,
+		last_failure DATETIME NOT NULL,
+		locked_until DATETIME
+	);`
+
+	_, err = DB.Exec(createLoginAttemptsTable)
+	if err != nil {
+		panic("Could not create login_attempts table: " + err.Error())
+	}
+
+	createTOTPCredentialsTable := `
+	CREATE TABLE IF NOT EXISTS totp_credentials (
+		user_id INTEGER PRIMARY KEY,
+		secret TEXT NOT NULL,
+		last_used_step INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		confirmed_at DATETIME,
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createTOTPCredentialsTable)
+	if err != nil {
+		panic("Could not create totp_credentials table: " + err.Error())
+	}
+
+	createRecoveryCodesTable := `
+	CREATE TABLE IF NOT EXISTS recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		code_hash TEXT NOT NULL,
+		used_at DATETIME,
+		FOREIGN KEY(user_id) REFERENCES users(id)
+	);`
+
+	_, err = DB.Exec(createRecoveryCodesTable)
+	if err != nil {
+		panic("Could not create recovery_codes table: " + err.Error())
+	}
+}
+
+// migrateIsAdminToRoles replaces the is_admin flag of older versions with rows in user_roles
+func migrateIsAdminToRoles() {
+	if !hasColumn("users", "is_admin") {
+		return
+	}
+
+	migrations := []string{
+		`INSERT OR IGNORE INTO user_roles (user_id, role, granted_by, granted_at) SELECT id, 'advertiser', NULL, CURRENT_TIMESTAMP FROM users`,
+		`INSERT OR IGNORE INTO user_roles (user_id, role, granted_by, granted_at) SELECT id, 'admin', NULL, CURRENT_TIMESTAMP FROM users WHERE is_admin`,
+		`ALTER TABLE users DROP COLUMN is_admin`,
+	}
+	for _, migration := range migrations {
+		if _, err := DB.Exec(migration); err != nil {
+			panic("Could not migrate is_admin to user_roles: " + err.Error())
+		}
+	}
+}
+
+// migrateSystemActor makes column nullable in a table created by an older version, which declared it NOT NULL
+// and stored 0 for changes the application made on its own; those are NULL now, since 0 is no user. SQLite cannot
+// drop a NOT NULL constraint, so the table is rebuilt from create, its current definition.
+func migrateSystemActor(table, column, create string) {
+	var notNull bool
+	err := DB.QueryRow(`SELECT "notnull" FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&notNull)
+	if err != nil {
+		panic("Could not inspect " + table + " table: " + err.Error())
+	}
+
+	if notNull {
+		columns := columnNames(table)
+		migrations := []string{
+			"ALTER TABLE " + table + " RENAME TO " + table + "_old",
+			create,
+			"INSERT INTO " + table + " (" + columns + ") SELECT " + columns + " FROM " + table + "_old",
+			"DROP TABLE " + table + "_old",
+		}
+		for _, migration := range migrations {
+			if _, err := DB.Exec(migration); err != nil {
+				panic("Could not make " + table + "." + column + " nullable: " + err.Error())
+			}
+		}
+	}
+
+	if _, err := DB.Exec("UPDATE " + table + " SET " + column + " = NULL WHERE " + column + " = 0"); err != nil {
+		panic("Could not migrate " + table + "." + column + " to NULL: " + err.Error())
+	}
+}
+
+// migrateVerifiedAt adds verified_at, counting accounts created before email verification existed as verified
+func migrateVerifiedAt() {
+	if hasColumn("users", "verified_at") {
+		return
+	}
+
+	addColumnIfMissing("users", "verified_at", "DATETIME")
+	if _, err := DB.Exec("UPDATE users SET verified_at = CURRENT_TIMESTAMP"); err != nil {
+		panic("Could not mark existing users as verified: " + err.Error())
+	}
+}
+
+// migrateAnnouncementDatesToUTC rewrites dates stored with their original offset by older versions in UTC.
+// Listings filter and page by comparing the stored text, which only orders instants correctly when every
+// date has the same offset.
+func migrateAnnouncementDatesToUTC() {
+	rows, err := DB.Query(`SELECT id, start_date, end_date, create_date FROM announcements
+		WHERE start_date NOT LIKE '%+00:00' OR end_date NOT LIKE '%+00:00' OR create_date NOT LIKE '%+00:00'`)
+	if err != nil {
+		panic("Could not read announcement dates: " + err.Error())
+	}
+
+	type dates struct {
+		id                             int64
+		startDate, endDate, createDate time.Time
+	}
+	var stale []dates
+	for rows.Next() {
+		var d dates
+		if err := rows.Scan(&d.id, &d.startDate, &d.endDate, &d.createDate); err != nil {
+			rows.Close()
+			panic("Could not read announcement dates: " + err.Error())
+		}
+		stale = append(stale, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		panic("Could not read announcement dates: " + err.Error())
+	}
+
+	for _, d := range stale {
+		_, err := DB.Exec("UPDATE announcements SET start_date = ?, end_date = ?, create_date = ? WHERE id = ?",
+			d.startDate.UTC(), d.endDate.UTC(), d.createDate.UTC(), d.id)
+		if err != nil {
+			panic("Could not migrate announcement dates to UTC: " + err.Error())
+		}
+	}
+}
+
+// statusNames mirrors models.Status: the name of each status, indexed by its old integer value
+var statusNames = []string{"pending", "accepted", "declined", "active", "deactivated"}
+
+// migrateStatusNames rewrites statuses stored as integers by older versions as their names
+func migrateStatusNames(table, column string) {
+	cases := ""
+	for i, name := range statusNames {
+		cases += fmt.Sprintf(" WHEN %d THEN '%s'", i, name)
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s = CASE CAST(%s AS INTEGER)%s END WHERE typeof(%s) = 'integer' AND %s BETWEEN 0 AND %d",
+		table, column, column, cases, column, column, len(statusNames)-1)
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		panic("Could not migrate " + table + "." + column + " to status names: " + err.Error())
+	}
+}
+
+// addColumnIfMissing upgrades tables created by an older version of createTables
+func addColumnIfMissing(table, column, definition string) {
+	if hasColumn(table, column) {
+		return
+	}
+
+	_, err := DB.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err != nil {
+		panic("Could not add " + column + " to " + table + " table: " + err.Error())
+	}
 }
 
-// TruncateUsersTable removes all records from the users table
+// columnNames returns the columns of the table, comma separated
+func columnNames(table string) string {
+	rows, err := DB.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		panic("Could not inspect " + table + " table: " + err.Error())
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			panic("Could not inspect " + table + " table: " + err.Error())
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func hasColumn(table, column string) bool {
+	rows, err := DB.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		panic("Could not inspect " + table + " table: " + err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			panic("Could not inspect " + table + " table: " + err.Error())
+		}
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// TruncateUsersTable removes all records from the users table, along with their roles and failed logins
 func TruncateUsersTable() {
-	_, err := DB.Exec("DELETE FROM users")
+	_, err := DB.Exec("DELETE FROM user_roles")
+	if err != nil {
+		log.Fatalf("Could not truncate user_roles table: %v", err)
+	}
+	_, err = DB.Exec("DELETE FROM login_attempts")
+	if err != nil {
+		log.Fatalf("Could not truncate login_attempts table: %v", err)
+	}
+	_, err = DB.Exec("DELETE FROM recovery_codes")
+	if err != nil {
+		log.Fatalf("Could not truncate recovery_codes table: %v", err)
+	}
+	_, err = DB.Exec("DELETE FROM totp_credentials")
+	if err != nil {
+		log.Fatalf("Could not truncate totp_credentials table: %v", err)
+	}
+	_, err = DB.Exec("DELETE FROM users")
 	if err != nil {
 		log.Fatalf("Could not truncate users table: %v", err)
 	}
 }
 
+func TruncateFlagsTable() {
+	_, err := DB.Exec("DELETE FROM flags")
+	if err != nil {
+		log.Fatalf("Could not truncate flags table: %v", err)
+	}
+}
+
 func TruncateAnnouncementsTable() {
 	_, err := DB.Exec("DELETE FROM announcements")
 	if err != nil {
@@ -1,16 +1,216 @@
 package helpers
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	"github.com/ngirimana/AnnounceIT/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes passwords with one algorithm and checks hashes it produced
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Recognizes reports whether the hash was produced by this algorithm
+	Recognizes(hash string) bool
+	Verify(password, hash string) bool
+	// NeedsRehash reports whether a recognized hash was made with other parameters than the hasher's
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idHasher hashes passwords with argon2id, stored in the PHC string format
+type Argon2idHasher struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var argon2Encoding = base64.RawStdEncoding
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		argon2Encoding.EncodeToString(salt), argon2Encoding.EncodeToString(key)), nil
+}
+
+func (Argon2idHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (Argon2idHasher) Verify(password, hash string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	return err != nil || params != h || len(salt) != argon2SaltLength || len(key) != argon2KeyLength
+}
+
+// parseArgon2id splits a hash made by Argon2idHasher.Hash into its parameters, salt and key
+func parseArgon2id(hash string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	salt, err := argon2Encoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := argon2Encoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2 key")
+	}
+	return params, salt, key, nil
+}
+
+// BcryptHasher is what passwords used to be hashed with; it is kept to verify them until users log in again
+type BcryptHasher struct {
+	Cost int
+}
 
+func (h BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	return string(bytes), err
+}
+
+func (BcryptHasher) Recognizes(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+func (BcryptHasher) Verify(password, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+var (
+	// parsedHashers caches the hashers so that their parameters are not read again for every password
+	parsedHashersMu sync.RWMutex
+	parsedHashers   []PasswordHasher
+)
+
+// loadPasswordHashers returns the hasher for new passwords, chosen by PASSWORD_HASHER, followed by the others.
+// Parameters out of range are refused rather than wrapped around or left for the hash functions to trip over.
+func loadPasswordHashers() ([]PasswordHasher, error) {
+	memory := config.Int("ARGON2_MEMORY_KIB", 64*1024)
+	iterations := config.Int("ARGON2_ITERATIONS", 3)
+	parallelism := config.Int("ARGON2_PARALLELISM", 2)
+	cost := config.Int("BCRYPT_COST", 14)
+	switch {
+	case parallelism < 1 || parallelism > math.MaxUint8:
+		return nil, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and %d", math.MaxUint8)
+	case iterations < 1 || int64(iterations) > math.MaxUint32:
+		return nil, fmt.Errorf("ARGON2_ITERATIONS must be between 1 and %d", uint32(math.MaxUint32))
+	// argon2 needs 8 KiB per thread and would silently use more than configured
+	case memory < 8*parallelism || int64(memory) > math.MaxUint32:
+		return nil, fmt.Errorf("ARGON2_MEMORY_KIB must be between 8 times ARGON2_PARALLELISM and %d", uint32(math.MaxUint32))
+	case cost < bcrypt.MinCost || cost > bcrypt.MaxCost:
+		return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
 
+	argon := Argon2idHasher{Memory: uint32(memory), Iterations: uint32(iterations), Parallelism: uint8(parallelism)}
+	bcryptHasher := BcryptHasher{Cost: cost}
+	switch algorithm := config.String("PASSWORD_HASHER", "argon2id"); algorithm {
+	case "argon2id":
+		return []PasswordHasher{argon, bcryptHasher}, nil
+	case "bcrypt":
+		return []PasswordHasher{bcryptHasher, argon}, nil
+	default:
+		return nil, fmt.Errorf("PASSWORD_HASHER must be argon2id or bcrypt, not %q", algorithm)
+	}
 }
 
+// CheckPasswordHasher reads the password hashing configuration passwords are hashed and checked with from then on,
+// reporting a misconfiguration so that it can be caught at startup. It has to be called again when it changes.
+func CheckPasswordHasher() error {
+	hashers, err := loadPasswordHashers()
+	if err != nil {
+		return err
+	}
+	parsedHashersMu.Lock()
+	parsedHashers = hashers
+	parsedHashersMu.Unlock()
+	return nil
+}
+
+// passwordHashers returns the hashers read by CheckPasswordHasher, reading them first if it has not been called yet
+func passwordHashers() ([]PasswordHasher, error) {
+	parsedHashersMu.RLock()
+	hashers := parsedHashers
+	parsedHashersMu.RUnlock()
+	if hashers == nil {
+		if err := CheckPasswordHasher(); err != nil {
+			return nil, err
+		}
+		return passwordHashers()
+	}
+	return hashers, nil
+}
+
+// HashPassword hashes a new password with the configured algorithm
+func HashPassword(password string) (string, error) {
+	hashers, err := passwordHashers()
+	if err != nil {
+		return "", err
+	}
+	return hashers[0].Hash(password)
+}
+
+// CheckPassword verifies a password against a hash made by any supported algorithm
 func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	hashers, err := passwordHashers()
+	if err != nil {
+		return false
+	}
+	for _, hasher := range hashers {
+		if hasher.Recognizes(hash) {
+			return hasher.Verify(password, hash)
+		}
+	}
+	return false
+}
 
-	return err == nil
+// PasswordNeedsRehash reports whether a hash was made with another algorithm or other parameters than
+// new passwords get, so that it should be replaced the next time the password is known
+func PasswordNeedsRehash(hash string) bool {
+	hashers, err := passwordHashers()
+	if err != nil {
+		return false
+	}
+	current := hashers[0]
+	return !current.Recognizes(hash) || current.NeedsRehash(hash)
 }
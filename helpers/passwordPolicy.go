@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ngirimana/AnnounceIT/config"
+)
+
+// breachedPasswords caches the breached-password list by path, so that it is read once
+var breachedPasswords struct {
+	sync.Mutex
+	path      string
+	passwords map[string]struct{}
+}
+
+// loadBreachedPasswords reads a list of passwords, one per line, compared case-insensitively
+func loadBreachedPasswords(path string) (map[string]struct{}, error) {
+	breachedPasswords.Lock()
+	defer breachedPasswords.Unlock()
+	if breachedPasswords.path == path {
+		return breachedPasswords.passwords, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	passwords := map[string]struct{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	breachedPasswords.path = path
+	breachedPasswords.passwords = passwords
+	return passwords, nil
+}
+
+// CheckPasswordStrength enforces the minimum length, PASSWORD_MIN_LENGTH characters, and rejects passwords on
+// the list in PASSWORD_BREACHED_LIST. It returns why the password is refused, to show to the user, or an empty
+// string for an acceptable password; err is only set when the list cannot be read.
+func CheckPasswordStrength(password string) (problem string, err error) {
+	minLength := config.Int("PASSWORD_MIN_LENGTH", 8)
+	if utf8.RuneCountInString(password) < minLength {
+		return fmt.Sprintf("Password must be at least %d characters", minLength), nil
+	}
+
+	path := config.String("PASSWORD_BREACHED_LIST", "")
+	if path == "" {
+		return "", nil
+	}
+	passwords, err := loadBreachedPasswords(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read the breached-password list: %w", err)
+	}
+	if _, ok := passwords[strings.ToLower(password)]; ok {
+		return "This password has appeared in a data breach; choose another one", nil
+	}
+	return "", nil
+}
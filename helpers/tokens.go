@@ -1,45 +1,305 @@
 package helpers
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/ngirimana/AnnounceIT/config"
 )
 
-const secretKey = "1234534sdgfdsbdvccxvfsdgf"
+// signingKeys holds the secrets tokens may be verified with, by key ID, and the ID of the one new tokens are signed with
+type signingKeys struct {
+	current string
+	secrets map[string][]byte
+}
+
+var (
+	ephemeralKeyOnce sync.Once
+	ephemeralKey     []byte
+
+	// parsedKeys caches the signing keys so that they are not parsed again for every token
+	parsedKeysMu sync.RWMutex
+	parsedKeys   *signingKeys
+)
+
+// loadSigningKeys reads JWT_SIGNING_KEYS, a comma-separated list of kid:secret pairs, and JWT_SIGNING_KEY_ID.
+// Keeping a retired key in the list while signing with a new one lets existing tokens expire naturally.
+func loadSigningKeys() (signingKeys, error) {
+	keys := signingKeys{secrets: map[string][]byte{}}
+
+	list := config.String("JWT_SIGNING_KEYS", "")
+	if list == "" {
+		// Without configured keys every restart logs everyone out, which is only acceptable in development
+		ephemeralKeyOnce.Do(func() {
+			log.Print("JWT_SIGNING_KEYS is not set, signing tokens with a random key")
+			ephemeralKey = make([]byte, 32)
+			if _, err := rand.Read(ephemeralKey); err != nil {
+				log.Fatalf("Could not generate a signing key: %v", err)
+			}
+		})
+		keys.current = "ephemeral"
+		keys.secrets[keys.current] = ephemeralKey
+		return keys, nil
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || kid == "" || secret == "" {
+			return keys, errors.New("JWT_SIGNING_KEYS must be a comma-separated list of kid:secret pairs")
+		}
+		if keys.current == "" {
+			keys.current = kid
+		}
+		keys.secrets[kid] = []byte(secret)
+	}
 
-func GenerateToken(email string, userId int64) (string, error) {
+	if kid := config.String("JWT_SIGNING_KEY_ID", ""); kid != "" {
+		if _, ok := keys.secrets[kid]; !ok {
+			return keys, fmt.Errorf("JWT_SIGNING_KEY_ID %q is not in JWT_SIGNING_KEYS", kid)
+		}
+		keys.current = kid
+	}
+	return keys, nil
+}
+
+// keyFunc picks the verification key named by the kid header of a token
+func (keys signingKeys) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	secret, ok := keys.secrets[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return secret, nil
+}
+
+// CheckSigningKeys parses the signing keys tokens are signed and verified with from then on, reporting a
+// misconfiguration so that it can be caught at startup. It has to be called again when the keys change.
+func CheckSigningKeys() error {
+	keys, err := loadSigningKeys()
+	if err != nil {
+		return err
+	}
+	parsedKeysMu.Lock()
+	parsedKeys = &keys
+	parsedKeysMu.Unlock()
+	return nil
+}
+
+// currentSigningKeys returns the keys parsed by CheckSigningKeys, parsing them first if it has not been called yet
+func currentSigningKeys() (signingKeys, error) {
+	parsedKeysMu.RLock()
+	keys := parsedKeys
+	parsedKeysMu.RUnlock()
+	if keys == nil {
+		if err := CheckSigningKeys(); err != nil {
+			return signingKeys{}, err
+		}
+		return currentSigningKeys()
+	}
+	return *keys, nil
+}
+
+func tokenIssuer() string {
+	return config.String("JWT_ISSUER", "announceit")
+}
+
+func tokenAudience() string {
+	return config.String("JWT_AUDIENCE", "announceit-api")
+}
+
+// GenerateToken signs a JWT for the user; tokenVersion must match the user's current version for the token to be accepted,
+// and the session, unless it is 0, must not have been revoked.
+// The roles claim tells clients what the user may do; the server re-reads roles on every request.
+func GenerateToken(email string, userId int64, tokenVersion int64, sessionId int64, roles []string) (string, error) {
+	keys, err := currentSigningKeys()
+	if err != nil {
+		return "", err
+	}
+
+	// The token ID lets a single token be revoked on logout
+	tokenId, err := GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	ttl := time.Duration(config.Int("ACCESS_TOKEN_TTL_MINUTES", 60)) * time.Minute
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":    tokenId,
 		"email":  email,
 		"userId": userId,
-		"exp":    time.Now().Add(time.Hour * 8766).Unix(),
+		"ver":    tokenVersion,
+		"sid":    sessionId,
+		"roles":  roles,
+		"iss":    tokenIssuer(),
+		"aud":    tokenAudience(),
+		"iat":    now.Unix(),
+		"exp":    now.Add(ttl).Unix(),
 	})
-	return token.SignedString([]byte(secretKey))
+	token.Header["kid"] = keys.current
+	return token.SignedString(keys.secrets[keys.current])
 }
 
-func VerifyToken(tokenString string) (int64, error) {
-	parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		_, OK := token.Method.(*jwt.SigningMethodHMAC)
-		if !OK {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secretKey), nil
-	})
+// AccessClaims are the claims of a verified access token the server relies on
+type AccessClaims struct {
+	UserID       int64
+	TokenVersion int64
+	TokenID      string
+	ExpiresAt    time.Time
+	// SessionID is 0 for tokens not tied to a session
+	SessionID int64
+}
+
+// VerifyToken checks the signature, key ID, issuer, audience and expiry of a JWT and returns the claims it carries
+func VerifyToken(tokenString string) (*AccessClaims, error) {
+	keys, err := currentSigningKeys()
 	if err != nil {
-		return 0, errors.New("could not parse token")
+		return nil, err
+	}
+
+	parsedToken, err := jwt.Parse(tokenString, keys.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer()),
+		jwt.WithAudience(tokenAudience()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, errors.New("could not parse token")
 	}
 	IsValidToken := parsedToken.Valid
 
 	if !IsValidToken {
-		return 0, errors.New("invalid token")
+		return nil, errors.New("invalid token")
+	}
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("could not parse claims")
+	}
+
+	userId, ok := claims["userId"].(float64)
+	if !ok || userId <= 0 {
+		return nil, errors.New("token has no valid userId claim")
+	}
+	tokenId, ok := claims["jti"].(string)
+	if !ok || tokenId == "" {
+		return nil, errors.New("token has no jti claim")
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		return nil, errors.New("could not parse claims")
+	}
+	tokenVersion, ok := claims["ver"].(float64)
+	if !ok {
+		return nil, errors.New("token has no ver claim")
+	}
+	// Without a session the token could not be revoked by signing its device out
+	sessionId, ok := claims["sid"].(float64)
+	if !ok || sessionId <= 0 {
+		return nil, errors.New("token has no valid sid claim")
+	}
+	return &AccessClaims{
+		UserID:       int64(userId),
+		TokenVersion: int64(tokenVersion),
+		TokenID:      tokenId,
+		ExpiresAt:    expiresAt.Time,
+		SessionID:    int64(sessionId),
+	}, nil
+}
+
+// challengeTTL is how long a user has to enter their two-factor code after the password
+const challengeTTL = 5 * time.Minute
+
+// challengeAudience keeps challenge tokens from being accepted as access tokens, and the other way round
+func challengeAudience() string {
+	return tokenAudience() + ":2fa"
+}
+
+// verificationAudience does the same for email verification tokens
+func verificationAudience() string {
+	return tokenAudience() + ":verify-email"
+}
+
+// signPurposeToken signs a token that is only accepted by the verifier for its audience
+func signPurposeToken(audience string, ttl time.Duration, claims jwt.MapClaims) (string, error) {
+	keys, err := currentSigningKeys()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims["iss"] = tokenIssuer()
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = keys.current
+	return token.SignedString(keys.secrets[keys.current])
+}
+
+// parsePurposeToken verifies a token signed by signPurposeToken for the audience and returns its user ID and claims
+func parsePurposeToken(tokenString, audience string) (int64, jwt.MapClaims, error) {
+	keys, err := currentSigningKeys()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	parsedToken, err := jwt.Parse(tokenString, keys.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer()),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !parsedToken.Valid {
+		return 0, nil, errors.New("invalid token")
 	}
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, errors.New("could not parse claims")
+		return 0, nil, errors.New("could not parse claims")
 	}
 
-	userId := int64(claims["userId"].(float64))
-	return userId, nil
+	userId, ok := claims["userId"].(float64)
+	if !ok || userId <= 0 {
+		return 0, nil, errors.New("token has no valid userId claim")
+	}
+	return int64(userId), claims, nil
+}
+
+// GenerateChallengeToken signs the short-lived token a user with two-factor authentication gets for a correct password
+func GenerateChallengeToken(userId int64, tokenVersion int64) (string, error) {
+	return signPurposeToken(challengeAudience(), challengeTTL, jwt.MapClaims{"userId": userId, "ver": tokenVersion})
+}
+
+// VerifyChallengeToken checks a challenge token and returns the user ID and token version it carries
+func VerifyChallengeToken(tokenString string) (int64, int64, error) {
+	userId, claims, err := parsePurposeToken(tokenString, challengeAudience())
+	if err != nil {
+		return 0, 0, err
+	}
+	tokenVersion, _ := claims["ver"].(float64)
+	return userId, int64(tokenVersion), nil
+}
+
+// GenerateVerificationToken signs the token emailed to a new user to prove they own the address
+func GenerateVerificationToken(userId int64, email string) (string, error) {
+	ttl := config.Duration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	return signPurposeToken(verificationAudience(), ttl, jwt.MapClaims{"userId": userId, "email": email})
+}
+
+// VerifyVerificationToken checks an email verification token and returns the user ID and address it was issued for
+func VerifyVerificationToken(tokenString string) (int64, string, error) {
+	userId, claims, err := parsePurposeToken(tokenString, verificationAudience())
+	if err != nil {
+		return 0, "", err
+	}
+	email, ok := claims["email"].(string)
+	if !ok || email == "" {
+		return 0, "", errors.New("token has no email claim")
+	}
+	return userId, email, nil
 }
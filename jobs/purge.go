@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// PurgeDeletedAnnouncements hard-deletes soft-deleted announcements older than retention, checking every interval
+func PurgeDeletedAnnouncements(announcements models.AnnouncementStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := announcements.PurgeDeleted(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Could not purge deleted announcements: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted announcements", purged)
+		}
+		<-ticker.C
+	}
+}
+
+// PurgeExpiredTokens deletes expired refresh tokens and revoked access tokens, and failed logins that no longer
+// count towards a lockout, checking every interval
+func PurgeExpiredTokens(sessions models.SessionStore, lockouts lockout.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		purged, err := sessions.PurgeExpired(context.Background(), now)
+		if err != nil {
+			log.Printf("Could not purge expired tokens: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d expired tokens", purged)
+		}
+		purged, err = lockout.PurgeLoginAttempts(context.Background(), lockouts, now)
+		if err != nil {
+			log.Printf("Could not purge login attempts: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d stale login attempts", purged)
+		}
+		<-ticker.C
+	}
+}
+
+// AnonymizeDeletedAccounts anonymizes accounts whose deletion grace period has ended, checking every interval
+func AnonymizeDeletedAccounts(users models.UserStore, lockouts lockout.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		anonymized, err := models.AnonymizeDueAccounts(context.Background(), users, lockouts, time.Now())
+		if err != nil {
+			log.Printf("Could not anonymize deleted accounts: %v", err)
+		} else if anonymized > 0 {
+			log.Printf("Anonymized %d deleted accounts", anonymized)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,84 @@
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Attempts is the failure history of one key, such as an account or a client IP
+type Attempts struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Store keeps failure counters; implementations must make Increment atomic
+type Store interface {
+	// Get returns the attempts for key, or the zero value when there are none
+	Get(ctx context.Context, key string) (Attempts, error)
+	// Increment records a failure at now, restarting the count when the previous failure is older than window
+	Increment(ctx context.Context, key string, now time.Time, window time.Duration) (Attempts, error)
+	// LockUntil rejects attempts for key until the given time
+	LockUntil(ctx context.Context, key string, until time.Time) error
+	// Reset forgets every failure for key
+	Reset(ctx context.Context, key string) error
+	// Purge forgets the keys whose last failure was before staleBefore and that are no longer locked at now,
+	// returning how many it forgot
+	Purge(ctx context.Context, staleBefore, now time.Time) (int64, error)
+}
+
+// Policy decides when repeated failures lock a key out, and for how long
+type Policy struct {
+	// Threshold is the number of failures that triggers the first lockout; 0 disables lockouts
+	Threshold int
+	// BaseLockout is the first lockout; it doubles with every further failure, up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// Window is how long a failure counts against the key
+	Window time.Duration
+}
+
+// lockout returns how long the key is locked after its nth failure
+func (p Policy) lockout(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+	duration := p.BaseLockout
+	for i := p.Threshold; i < failures && duration < p.MaxLockout; i++ {
+		duration *= 2
+	}
+	return min(duration, p.MaxLockout)
+}
+
+// Limiter applies a policy to the keys of one kind, kept apart in the store by a prefix
+type Limiter struct {
+	Store  Store
+	Policy Policy
+	Prefix string
+}
+
+// RetryAfter returns how long the key is still locked out, or 0 when attempts are allowed
+func (l Limiter) RetryAfter(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	attempts, err := l.Store.Get(ctx, l.Prefix+key)
+	if err != nil {
+		return 0, err
+	}
+	return max(attempts.LockedUntil.Sub(now), 0), nil
+}
+
+// Fail records a failed attempt and locks the key out once the policy says so
+func (l Limiter) Fail(ctx context.Context, key string, now time.Time) error {
+	attempts, err := l.Store.Increment(ctx, l.Prefix+key, now, l.Policy.Window)
+	if err != nil {
+		return err
+	}
+	if duration := l.Policy.lockout(attempts.Failures); duration > 0 {
+		return l.Store.LockUntil(ctx, l.Prefix+key, now.Add(duration))
+	}
+	return nil
+}
+
+// Reset clears the failures of the key, unlocking it
+func (l Limiter) Reset(ctx context.Context, key string) error {
+	return l.Store.Reset(ctx, l.Prefix+key)
+}
@@ -0,0 +1,62 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutBackoff(t *testing.T) {
+	limiter := Limiter{
+		Store:  NewMemoryStore(),
+		Policy: Policy{Threshold: 2, BaseLockout: time.Minute, MaxLockout: 5 * time.Minute, Window: time.Hour},
+	}
+	now := time.Now()
+
+	expected := []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	for i, wait := range expected {
+		assert.NoError(t, limiter.Fail(context.Background(), "key", now))
+		retryAfter, err := limiter.RetryAfter(context.Background(), "key", now)
+		assert.NoError(t, err)
+		assert.Equal(t, wait, retryAfter, "after failure %d", i+1)
+	}
+
+	// Failures older than the window no longer count
+	later := now.Add(2 * time.Hour)
+	assert.NoError(t, limiter.Fail(context.Background(), "key", later))
+	retryAfter, err := limiter.RetryAfter(context.Background(), "key", later)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), retryAfter)
+}
+
+func TestPurgeStaleAttempts(t *testing.T) {
+	store := NewMemoryStore()
+	limiter := Limiter{
+		Store:  store,
+		Policy: Policy{Threshold: 1, BaseLockout: 2 * time.Hour, MaxLockout: 2 * time.Hour, Window: time.Hour},
+	}
+	now := time.Now()
+
+	assert.NoError(t, limiter.Fail(context.Background(), "locked", now.Add(-90*time.Minute)))
+	_, err := store.Increment(context.Background(), "stale", now.Add(-90*time.Minute), time.Hour)
+	assert.NoError(t, err)
+	_, err = store.Increment(context.Background(), "recent", now.Add(-time.Minute), time.Hour)
+	assert.NoError(t, err)
+
+	purged, err := store.Purge(context.Background(), now.Add(-time.Hour), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	// A key still locked out is kept, so purging cannot lift a lockout early
+	retryAfter, err := limiter.RetryAfter(context.Background(), "locked", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, retryAfter)
+	attempts, err := store.Get(context.Background(), "recent")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+	attempts, err = store.Get(context.Background(), "stale")
+	assert.NoError(t, err)
+	assert.Equal(t, Attempts{}, attempts)
+}
@@ -0,0 +1,76 @@
+package lockout
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/config"
+)
+
+func loginPolicy(thresholdKey string, threshold int) Policy {
+	return Policy{
+		Threshold:   config.Int(thresholdKey, threshold),
+		BaseLockout: config.Duration("LOGIN_LOCKOUT_BASE", time.Minute),
+		MaxLockout:  config.Duration("LOGIN_LOCKOUT_MAX", time.Hour),
+		Window:      config.Duration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+	}
+}
+
+// Accounts counts failed logins per email address, as returned by AccountKey
+func Accounts(store Store) Limiter {
+	return Limiter{Store: store, Policy: loginPolicy("LOGIN_LOCKOUT_THRESHOLD", 5), Prefix: "account:"}
+}
+
+// ClientIPs counts failed logins per client IP, with a higher threshold since addresses can be shared
+func ClientIPs(store Store) Limiter {
+	return Limiter{Store: store, Policy: loginPolicy("LOGIN_IP_LOCKOUT_THRESHOLD", 20), Prefix: "ip:"}
+}
+
+// AccountKey is the key Accounts counts failures of an email address under, whatever its case
+func AccountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginRetryAfter returns how long logins to the account from the client IP are still locked out, or 0
+// when they are allowed. Locked out callers must be turned away before the password is hashed, which is
+// what makes guessing expensive.
+func LoginRetryAfter(ctx context.Context, store Store, email, ip string, now time.Time) (time.Duration, error) {
+	accountWait, err := Accounts(store).RetryAfter(ctx, AccountKey(email), now)
+	if err != nil {
+		return 0, err
+	}
+	ipWait, err := ClientIPs(store).RetryAfter(ctx, ip, now)
+	if err != nil {
+		return 0, err
+	}
+	return max(accountWait, ipWait), nil
+}
+
+// FailLogin records a wrong password or code for the account from the client IP. A failure to record it
+// is only logged, since the caller is turned away either way.
+func FailLogin(ctx context.Context, store Store, email, ip string, now time.Time) {
+	key := AccountKey(email)
+	if err := Accounts(store).Fail(ctx, key, now); err != nil {
+		log.Printf("Could not record failed login for %s: %v", key, err)
+	}
+	if err := ClientIPs(store).Fail(ctx, ip, now); err != nil {
+		log.Printf("Could not record failed login from %s: %v", ip, err)
+	}
+}
+
+// SucceedLogin forgets the failed logins of the account; those of the client IP keep counting,
+// so that one account an attacker owns does not clear the way to guessing others
+func SucceedLogin(ctx context.Context, store Store, email string) {
+	key := AccountKey(email)
+	if err := Accounts(store).Reset(ctx, key); err != nil {
+		log.Printf("Could not reset failed logins for %s: %v", key, err)
+	}
+}
+
+// PurgeLoginAttempts forgets accounts and client IPs whose failures no longer count and that are not locked out
+func PurgeLoginAttempts(ctx context.Context, store Store, now time.Time) (int64, error) {
+	window := config.Duration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute)
+	return store.Purge(ctx, now.Add(-window), now)
+}
@@ -0,0 +1,68 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps counters in the process; they are lost on restart and not shared between instances
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]Attempts
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: map[string]Attempts{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *MemoryStore) Increment(ctx context.Context, key string, now time.Time, window time.Duration) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := s.attempts[key]
+	if now.Sub(attempts.LastFailure) > window {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailure = now
+	s.attempts[key] = attempts
+	return attempts, nil
+}
+
+func (s *MemoryStore) LockUntil(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := s.attempts[key]
+	attempts.LockedUntil = until
+	s.attempts[key] = attempts
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryStore) Purge(ctx context.Context, staleBefore, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for key, attempts := range s.attempts {
+		if attempts.LastFailure.Before(staleBefore) && !attempts.LockedUntil.After(now) {
+			delete(s.attempts, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,59 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLStore keeps counters in the login_attempts table, so they survive restarts and are shared between instances
+type SQLStore struct {
+	DB *sql.DB
+}
+
+func (s SQLStore) Get(ctx context.Context, key string) (Attempts, error) {
+	var attempts Attempts
+	var lockedUntil sql.NullTime
+	query := "SELECT failures, last_failure, locked_until FROM login_attempts WHERE key = ?"
+	err := s.DB.QueryRowContext(ctx, query, key).Scan(&attempts.Failures, &attempts.LastFailure, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Attempts{}, nil
+	}
+	attempts.LockedUntil = lockedUntil.Time
+	return attempts, err
+}
+
+func (s SQLStore) Increment(ctx context.Context, key string, now time.Time, window time.Duration) (Attempts, error) {
+	query := `
+	INSERT INTO login_attempts (key, failures, last_failure) VALUES (?, 1, ?)
+	ON CONFLICT(key) DO UPDATE SET
+		failures = CASE WHEN last_failure < ? THEN 1 ELSE failures + 1 END,
+		last_failure = excluded.last_failure
+	RETURNING failures, last_failure, locked_until`
+	var attempts Attempts
+	var lockedUntil sql.NullTime
+	now = now.UTC()
+	err := s.DB.QueryRowContext(ctx, query, key, now, now.Add(-window)).Scan(&attempts.Failures, &attempts.LastFailure, &lockedUntil)
+	attempts.LockedUntil = lockedUntil.Time
+	return attempts, err
+}
+
+func (s SQLStore) LockUntil(ctx context.Context, key string, until time.Time) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE login_attempts SET locked_until = ? WHERE key = ?", until.UTC(), key)
+	return err
+}
+
+func (s SQLStore) Reset(ctx context.Context, key string) error {
+	_, err := s.DB.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = ?", key)
+	return err
+}
+
+func (s SQLStore) Purge(ctx context.Context, staleBefore, now time.Time) (int64, error) {
+	query := "DELETE FROM login_attempts WHERE last_failure < ? AND (locked_until IS NULL OR locked_until <= ?)"
+	result, err := s.DB.ExecContext(ctx, query, staleBefore.UTC(), now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
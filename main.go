@@ -1,10 +1,22 @@
 package main
 
 import (
+	"context"
+	"log"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/controllers"
 	"github.com/ngirimana/AnnounceIT/db"
 	_ "github.com/ngirimana/AnnounceIT/docs" // Replace with your module name to match the generated docs import
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/jobs"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
+	"github.com/ngirimana/AnnounceIT/notifications"
 	"github.com/ngirimana/AnnounceIT/routes"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -27,14 +39,107 @@ import (
 
 // @schemes http https
 func main() {
+	if err := helpers.CheckSigningKeys(); err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+	if err := helpers.CheckPasswordHasher(); err != nil {
+		log.Fatalf("Invalid password hashing configuration: %v", err)
+	}
+
 	db.InitDB()
+	notifications.Default = newNotifier()
+	users := models.SQLUserStore{DB: db.DB}
+	announcements := models.SQLAnnouncementStore{DB: db.DB}
+	sessions := models.SQLSessionStore{DB: db.DB}
+	apiKeys := models.SQLAPIKeyStore{DB: db.DB}
+	twoFactor := models.SQLTwoFactorStore{DB: db.DB}
+	blacklist := models.SQLBlacklistStore{DB: db.DB}
+	flags := models.SQLFlagStore{DB: db.DB}
+	lockouts := newLockoutStore()
+
+	middlewares.Authenticators = []middlewares.Authenticator{
+		middlewares.JWTAuthenticator{Users: users, Sessions: sessions, TwoFactor: twoFactor},
+	}
+	middlewares.APIKeys = middlewares.APIKeyAuthenticator{Users: users, Keys: apiKeys, TwoFactor: twoFactor}
+	// HTTP Basic is meant for internal tools only
+	if config.Bool("AUTH_BASIC_ENABLED", false) {
+		basic := middlewares.BasicAuthenticator{Users: users, TwoFactor: twoFactor, Lockouts: lockouts}
+		middlewares.Authenticators = append(middlewares.Authenticators, basic)
+	}
+
+	// Signup only creates advertisers, so the first admin is appointed through the environment
+	if email := config.String("ADMIN_EMAIL", ""); email != "" {
+		grantAdmin(users, email)
+	}
+
+	// Hard-delete soft-deleted announcements once they are older than the retention period
+	retention := config.Duration("ANNOUNCEMENT_RETENTION", 30*24*time.Hour)
+	go jobs.PurgeDeletedAnnouncements(announcements, retention, time.Hour)
+	go jobs.PurgeExpiredTokens(sessions, lockouts, time.Hour)
+	go jobs.AnonymizeDeletedAccounts(users, lockouts, time.Hour)
+
 	server := gin.Default()
 
 	// Swagger endpoint to serve the API documentation
 	server.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	routes.RegisterRoutes(server)
+	routes.RegisterRoutes(server,
+		&controllers.UserHandler{
+			Users:          users,
+			Announcements:  announcements,
+			Sessions:       sessions,
+			APIKeys:        apiKeys,
+			TwoFactor:      twoFactor,
+			PasswordResets: models.SQLPasswordResetStore{DB: db.DB},
+			Blacklist:      blacklist,
+			Flags:          flags,
+			Lockouts:       lockouts,
+		},
+		&controllers.AnnouncementHandler{Announcements: announcements, Users: users, Flags: flags, Blacklist: blacklist})
 
 	// Start the server on port 8000
 	server.Run(":8000")
 }
+
+func grantAdmin(users models.UserStore, email string) {
+	user, err := users.GetByEmail(context.Background(), email)
+	if err != nil {
+		log.Printf("ADMIN_EMAIL: no user with email %s", email)
+		return
+	}
+	if err := users.GrantRole(context.Background(), user.ID, models.RoleAdmin, models.SystemUserID); err != nil {
+		log.Printf("ADMIN_EMAIL: could not grant the admin role: %v", err)
+	}
+}
+
+// newLockoutStore counts failed logins in the database, or with LOGIN_ATTEMPT_STORE=memory in the process,
+// where they are lost on restart and not shared between instances
+func newLockoutStore() lockout.Store {
+	store := config.String("LOGIN_ATTEMPT_STORE", "sql")
+	if store == "memory" {
+		return lockout.NewMemoryStore()
+	}
+	if store != "sql" {
+		log.Fatalf("Invalid LOGIN_ATTEMPT_STORE %q: use sql or memory", store)
+	}
+	return lockout.SQLStore{DB: db.DB}
+}
+
+// newNotifier sends account email through SMTP_ADDR, or appends it to MAIL_FILE; without either it is only logged
+func newNotifier() notifications.Notifier {
+	var mailer notifications.Mailer
+	switch {
+	case config.String("SMTP_ADDR", "") != "":
+		mailer = notifications.SMTPMailer{
+			Addr:     config.String("SMTP_ADDR", ""),
+			Username: config.String("SMTP_USERNAME", ""),
+			Password: config.String("SMTP_PASSWORD", ""),
+			From:     config.String("MAIL_FROM", "no-reply@announceit.local"),
+		}
+	case config.String("MAIL_FILE", "") != "":
+		mailer = notifications.FileMailer{Path: config.String("MAIL_FILE", "")}
+	default:
+		return notifications.LogNotifier{}
+	}
+	return notifications.MailNotifier{Mailer: mailer, BaseURL: config.String("PUBLIC_BASE_URL", "http://localhost:8000")}
+}
@@ -1,25 +1,137 @@
 package middlewares
 
 import (
-	"net/http"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/models"
 )
 
+const realm = "announceit"
+
+// parseAuthorization splits an Authorization header into its scheme and credentials.
+// A header without a scheme is a bare JWT, as sent by clients written before the Bearer scheme was parsed.
+func parseAuthorization(header string) (string, string) {
+	scheme, credentials, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return "Bearer", scheme
+	}
+	return scheme, strings.TrimSpace(credentials)
+}
+
+// challenge sets a WWW-Authenticate header for every scheme in the chain, flagging the one that failed
+func challenge(context *gin.Context, failed string) {
+	for _, authenticator := range Authenticators {
+		value := authenticator.Scheme() + ` realm="` + realm + `"`
+		if authenticator.Scheme() == "Bearer" && strings.EqualFold(failed, "Bearer") {
+			value += `, error="invalid_token"`
+		}
+		context.Writer.Header().Add("WWW-Authenticate", value)
+	}
+}
+
+func unauthorized(context *gin.Context, failed, code, message string) {
+	challenge(context, failed)
+	abort(context, models.Unauthorized(code, message))
+}
+
+// TooManyAttempts turns away a caller who is locked out after failed logins, telling them how many seconds to wait
+func TooManyAttempts(context *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	context.Header("Retry-After", strconv.Itoa(seconds))
+	abort(context, models.TooManyRequests("too_many_attempts", "Too many failed login attempts, try again later").With("retry_after", seconds))
+}
+
 func Authenticate(context *gin.Context) {
-	token := context.Request.Header.Get("Authorization")
-	if token == "" {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization token is required"})
+	header := context.Request.Header.Get("Authorization")
+	if header == "" {
+		if key := context.Request.Header.Get("X-API-Key"); key != "" {
+			authenticateWithAPIKey(context, key)
+			return
+		}
+		unauthorized(context, "", "missing_credentials", "Authorization token is required")
+		return
+	}
+
+	scheme, credentials := parseAuthorization(header)
+	var authenticator Authenticator
+	for _, candidate := range Authenticators {
+		if strings.EqualFold(candidate.Scheme(), scheme) {
+			authenticator = candidate
+			break
+		}
+	}
+	if authenticator == nil {
+		unauthorized(context, "", "unsupported_scheme", "Unsupported authorization scheme")
+		return
+	}
+
+	principal, err := authenticator.Authenticate(context.Request.Context(), credentials, context.ClientIP())
+	var lockedOut *LockedOutError
+	switch {
+	case errors.As(err, &lockedOut):
+		TooManyAttempts(context, lockedOut.RetryAfter)
+		return
+	case errors.Is(err, ErrTokenRevoked):
+		unauthorized(context, scheme, "token_revoked", "Token has been revoked")
+		return
+	case errors.Is(err, ErrAccountSuspended):
+		abort(context, models.Forbidden("account_suspended", "Account suspended"))
+		return
+	case errors.Is(err, ErrInvalidCredentials) && authenticator.Scheme() == "Bearer":
+		unauthorized(context, scheme, "invalid_token", "Invalid token")
+		return
+	case errors.Is(err, ErrInvalidCredentials):
+		unauthorized(context, scheme, "invalid_credentials", "Invalid credentials")
+		return
+	case err != nil:
+		abort(context, fmt.Errorf("could not authenticate the request: %w", err))
 		return
 	}
 
-	userId, err := helpers.VerifyToken(token)
+	setPrincipal(context, principal)
+}
 
+func authenticateWithAPIKey(context *gin.Context, key string) {
+	principal, err := APIKeys.Authenticate(context.Request.Context(), key)
+	if errors.Is(err, ErrInvalidCredentials) {
+		unauthorized(context, "", "invalid_api_key", "Invalid API key")
+		return
+	}
+	if errors.Is(err, ErrAccountSuspended) {
+		abort(context, models.Forbidden("account_suspended", "Account suspended"))
+		return
+	}
 	if err != nil {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		abort(context, fmt.Errorf("could not authenticate the request: %w", err))
 		return
 	}
-	context.Set("userId", userId)
+	setPrincipal(context, principal)
+}
+
+func setPrincipal(context *gin.Context, principal *Principal) {
+	context.Set("principal", principal)
+	context.Set("userId", principal.UserID)
 	context.Next()
 }
+
+// CurrentPrincipal returns the caller set by Authenticate, or nil for anonymous requests
+func CurrentPrincipal(context *gin.Context) *Principal {
+	value, _ := context.Get("principal")
+	principal, _ := value.(*Principal)
+	return principal
+}
+
+// OptionalAuthenticate identifies the caller when a token or API key is sent, but lets anonymous requests through
+func OptionalAuthenticate(context *gin.Context) {
+	if context.Request.Header.Get("Authorization") == "" && context.Request.Header.Get("X-API-Key") == "" {
+		context.Next()
+		return
+	}
+	Authenticate(context)
+}
@@ -0,0 +1,233 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/config"
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/lockout"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+var (
+	// ErrInvalidCredentials is returned by an Authenticator when the credentials are malformed or wrong
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrTokenRevoked is returned by an Authenticator when valid credentials have been revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrAccountSuspended is returned by an Authenticator when the credentials belong to a suspended account
+	ErrAccountSuspended = errors.New("account suspended")
+)
+
+// LockedOutError is returned by an Authenticator that checks passwords when the account or client IP is locked
+// out after too many failures
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("locked out for %s", e.RetryAfter)
+}
+
+// Principal is the authenticated caller, whichever authenticator recognised the credentials
+type Principal struct {
+	UserID int64
+	Roles  []models.Role
+	// Method is the scheme the caller authenticated with, such as "Bearer" or "Basic"
+	Method string
+	// TokenID, TokenExpiresAt and SessionID are only set for JWTs, so that logout can revoke the token and its session
+	TokenID        string
+	TokenExpiresAt time.Time
+	SessionID      int64
+	// Scopes limits an API key to some of the permissions of its owner; nil means no limit
+	Scopes []models.Permission
+	// WithheldRoles are roles the user holds but may not use yet, such as admin before enrolling in two-factor authentication
+	WithheldRoles []models.Role
+}
+
+// Can reports whether the principal's roles grant the permission and its scopes, if any, allow it
+func (p *Principal) Can(permission models.Permission) bool {
+	if !models.HasPermission(p.Roles, permission) {
+		return false
+	}
+	if p.Scopes == nil {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator verifies the credentials of one Authorization scheme
+type Authenticator interface {
+	// Scheme is the case-insensitive Authorization scheme handled, as named in WWW-Authenticate challenges
+	Scheme() string
+	// Authenticate runs for every request, whose context and client IP it is given
+	Authenticate(ctx context.Context, credentials, clientIP string) (*Principal, error)
+}
+
+// Authenticators is the chain Authenticate picks from by scheme; main sets it up with the stores it opened
+var Authenticators []Authenticator
+
+// APIKeys checks the X-API-Key header, which Authenticate falls back to without an Authorization header;
+// main sets it up like Authenticators
+var APIKeys APIKeyAuthenticator
+
+// newPrincipal loads the roles of the user; they are read on every request so that a revoked role
+// or a suspension takes effect immediately
+func newPrincipal(ctx context.Context, users models.UserStore, twoFactor models.TwoFactorStore, userId int64, method string) (*Principal, error) {
+	suspended, err := users.IsSuspended(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if suspended {
+		return nil, ErrAccountSuspended
+	}
+
+	roles, err := users.Roles(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{UserID: userId, Roles: roles, Method: method}
+
+	if config.Bool("REQUIRE_ADMIN_2FA", false) && models.HasRole(roles, models.RoleAdmin) {
+		enrolled, err := twoFactor.Enabled(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !enrolled {
+			principal.Roles = nil
+			for _, role := range roles {
+				if role != models.RoleAdmin {
+					principal.Roles = append(principal.Roles, role)
+				}
+			}
+			principal.WithheldRoles = []models.Role{models.RoleAdmin}
+		}
+	}
+	return principal, nil
+}
+
+// JWTAuthenticator accepts access tokens issued by login and refresh
+type JWTAuthenticator struct {
+	Users     models.UserStore
+	Sessions  models.SessionStore
+	TwoFactor models.TwoFactorStore
+}
+
+func (JWTAuthenticator) Scheme() string { return "Bearer" }
+
+func (a JWTAuthenticator) Authenticate(ctx context.Context, token, _ string) (*Principal, error) {
+	claims, err := helpers.VerifyToken(token)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Resetting the password or logging out everywhere bumps the version, which revokes every token issued before
+	currentVersion, err := a.Users.TokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	revoked, err := a.Sessions.IsAccessTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked || currentVersion != claims.TokenVersion {
+		return nil, ErrTokenRevoked
+	}
+	// Signing a device out revokes its session, and with it every access token issued to the device
+	active, err := a.Sessions.Touch(ctx, claims.SessionID, claims.UserID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrTokenRevoked
+	}
+
+	principal, err := newPrincipal(ctx, a.Users, a.TwoFactor, claims.UserID, "Bearer")
+	if err != nil {
+		return nil, err
+	}
+	principal.TokenID = claims.TokenID
+	principal.TokenExpiresAt = claims.ExpiresAt
+	principal.SessionID = claims.SessionID
+	return principal, nil
+}
+
+// BasicAuthenticator accepts an email and password, for internal tools that cannot log in first.
+// It is not in the chain by default, since every request then pays for a password hash.
+// Wrong passwords count towards the same lockouts as those sent to login.
+type BasicAuthenticator struct {
+	Users     models.UserStore
+	TwoFactor models.TwoFactorStore
+	Lockouts  lockout.Store
+}
+
+func (BasicAuthenticator) Scheme() string { return "Basic" }
+
+func (a BasicAuthenticator) Authenticate(ctx context.Context, credentials, clientIP string) (*Principal, error) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	email, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	wait, err := lockout.LoginRetryAfter(ctx, a.Lockouts, email, clientIP, now)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &LockedOutError{RetryAfter: wait}
+	}
+
+	user := models.User{Email: email, Password: password}
+	if err := user.Authenticate(ctx, a.Users); err != nil {
+		lockout.FailLogin(ctx, a.Lockouts, email, clientIP, now)
+		return nil, ErrInvalidCredentials
+	}
+	// A password alone must not get around the second factor
+	twoFactor, err := a.TwoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor {
+		return nil, ErrInvalidCredentials
+	}
+	lockout.SucceedLogin(ctx, a.Lockouts, email)
+	return newPrincipal(ctx, a.Users, a.TwoFactor, user.ID, "Basic")
+}
+
+// APIKeyAuthenticator accepts the keys users create for integrations, sent in the X-API-Key header
+type APIKeyAuthenticator struct {
+	Users     models.UserStore
+	Keys      models.APIKeyStore
+	TwoFactor models.TwoFactorStore
+}
+
+func (a APIKeyAuthenticator) Authenticate(ctx context.Context, fullKey string) (*Principal, error) {
+	key, err := models.AuthenticateAPIKey(ctx, a.Keys, fullKey)
+	if errors.Is(err, models.ErrInvalidAPIKey) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := newPrincipal(ctx, a.Users, a.TwoFactor, key.UserID, "ApiKey")
+	if err != nil {
+		return nil, err
+	}
+	principal.Scopes = key.Scopes
+	return principal, nil
+}
@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+var statuses = map[models.Kind]int{
+	models.KindBadRequest:      http.StatusBadRequest,
+	models.KindUnauthorized:    http.StatusUnauthorized,
+	models.KindForbidden:       http.StatusForbidden,
+	models.KindNotFound:        http.StatusNotFound,
+	models.KindConflict:        http.StatusConflict,
+	models.KindValidation:      http.StatusUnprocessableEntity,
+	models.KindTooManyRequests: http.StatusTooManyRequests,
+}
+
+// RequestID tags every request with an ID, kept from the X-Request-ID header when the client or a proxy sent one,
+// and echoes it back so that a response can be matched with the server logs
+func RequestID(context *gin.Context) {
+	id := context.Request.Header.Get(requestIDHeader)
+	if id == "" || len(id) > 128 {
+		id = newRequestID()
+	}
+	context.Set("requestId", id)
+	context.Header(requestIDHeader, id)
+	context.Next()
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// HandleErrors answers the last error a handler attached with context.Error, unless a response was already written.
+// Domain errors become an RFC 7807 problem with their code and message; anything else is logged and answered
+// with a generic 500, so that internal details never reach the client.
+func HandleErrors(context *gin.Context) {
+	context.Next()
+
+	if len(context.Errors) == 0 || context.Writer.Written() {
+		return
+	}
+	err := context.Errors.Last().Err
+
+	var domain *models.Error
+	status, known := http.StatusInternalServerError, false
+	if errors.As(err, &domain) {
+		status, known = statuses[domain.Kind]
+	}
+	if !known {
+		log.Printf("Request %s %s %s failed: %v", context.GetString("requestId"), context.Request.Method, context.Request.URL.Path, err)
+		domain = &models.Error{Code: "internal_error", Message: "Something went wrong, please try again later"}
+		status = http.StatusInternalServerError
+	}
+
+	problem := gin.H{}
+	for key, value := range domain.Extra {
+		problem[key] = value
+	}
+	problem["type"] = "about:blank"
+	problem["title"] = http.StatusText(status)
+	problem["status"] = status
+	problem["detail"] = domain.Message
+	problem["instance"] = context.Request.URL.Path
+	problem["code"] = domain.Code
+	if id := context.GetString("requestId"); id != "" {
+		problem["request_id"] = id
+	}
+	if domain.Kind == models.KindValidation {
+		problem["fields"] = domain.Fields
+	}
+	// gin keeps a Content-Type that is already set
+	context.Header("Content-Type", "application/problem+json")
+	context.JSON(status, problem)
+}
+
+// abort stops the chain with err, left for HandleErrors to answer
+func abort(context *gin.Context, err error) {
+	context.Error(err)
+	context.Abort()
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ngirimana/AnnounceIT/models"
+)
+
+// RequirePermission must run after Authenticate and rejects callers none of whose roles grant the permission,
+// and API keys not scoped to it
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if !CallerCan(context, permission) {
+			// Say why when the permission comes with a role the user may not use until enrolling in two-factor authentication
+			if principal := CurrentPrincipal(context); principal != nil && models.HasPermission(principal.WithheldRoles, permission) {
+				abort(context, models.Forbidden("two_factor_required", "Two-factor authentication is required for this role").With("permission", permission))
+				return
+			}
+			abort(context, models.Forbidden("insufficient_permissions", "Insufficient permissions").With("permission", permission))
+			return
+		}
+		context.Next()
+	}
+}
+
+// CallerCan reports whether the caller set by Authenticate has the permission; anonymous callers have none
+func CallerCan(context *gin.Context, permission models.Permission) bool {
+	principal := CurrentPrincipal(context)
+	return principal != nil && principal.Can(permission)
+}
+
+// RejectAPIKeys must run after Authenticate and keeps API keys away from account management,
+// which only the user may do
+func RejectAPIKeys(context *gin.Context) {
+	if principal := CurrentPrincipal(context); principal != nil && principal.Method == "ApiKey" {
+		abort(context, models.Forbidden("api_key_not_allowed", "API keys cannot be used for this endpoint"))
+		return
+	}
+	context.Next()
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/helpers"
+	"github.com/ngirimana/AnnounceIT/lockout"
+)
+
+var (
+	// ErrDeletionScheduled is returned when asking to delete an account whose deletion is already scheduled
+	ErrDeletionScheduled = Conflict("deletion_already_scheduled", "Account deletion is already scheduled")
+	// ErrNoDeletionScheduled is returned when cancelling a deletion that was never scheduled
+	ErrNoDeletionScheduled = Conflict("no_deletion_scheduled", "No account deletion is scheduled")
+	// ErrLastAdminDeletion is returned when deleting an account would leave no admin
+	ErrLastAdminDeletion = Conflict("last_admin", "Cannot delete the account of the last admin")
+)
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ScheduleDeletion checks the password and schedules the account to be anonymized at the given time.
+// Until then the account keeps working, so that the user can change their mind.
+func (u *User) ScheduleDeletion(ctx context.Context, users UserStore, password string, at time.Time) error {
+	retrievedPassword, err := users.PasswordHash(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if !helpers.CheckPassword(password, retrievedPassword) {
+		return ErrInvalidCredentials
+	}
+
+	at = at.UTC()
+	if err := users.ScheduleDeletion(ctx, u.ID, at); err != nil {
+		return err
+	}
+	u.DeletionScheduledAt = &at
+	return nil
+}
+
+// AnonymizeDueAccounts anonymizes every account whose deletion was scheduled for now or earlier,
+// and returns how many it anonymized
+func AnonymizeDueAccounts(ctx context.Context, users UserStore, lockouts lockout.Store, now time.Time) (int64, error) {
+	ids, err := users.DueForDeletion(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var anonymized int64
+	for _, id := range ids {
+		user, err := users.GetByID(ctx, id)
+		if err != nil {
+			return anonymized, err
+		}
+		// Failed logins are counted under the email address, which is about to be lost
+		if err := lockout.Accounts(lockouts).Reset(ctx, lockout.AccountKey(user.Email)); err != nil {
+			return anonymized, err
+		}
+		err = users.Anonymize(ctx, id, now)
+		if errors.Is(err, ErrLastAdminDeletion) {
+			// Kept until another admin is appointed, or the user cancels the deletion
+			log.Printf("Not deleting the account of user %d, the last admin", id)
+			continue
+		}
+		if err != nil {
+			return anonymized, err
+		}
+		anonymized++
+	}
+	return anonymized, nil
+}
+
+func (s SQLUserStore) ScheduleDeletion(ctx context.Context, id int64, at time.Time) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE users SET deletion_scheduled_at = ? WHERE id = ? AND deletion_scheduled_at IS NULL AND deleted_at IS NULL"
+	err = affectedOne(tx.ExecContext(ctx, query, at.UTC(), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrDeletionScheduled
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkNotLastAdmin(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s SQLUserStore) CancelDeletion(ctx context.Context, id int64) error {
+	query := "UPDATE users SET deletion_scheduled_at = NULL WHERE id = ? AND deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL"
+	err := affectedOne(s.DB.ExecContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoDeletionScheduled
+	}
+	return err
+}
+
+func (s SQLUserStore) DueForDeletion(ctx context.Context, now time.Time) ([]int64, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT id FROM users WHERE deletion_scheduled_at <= ? AND deleted_at IS NULL", now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s SQLUserStore) Anonymize(ctx context.Context, id int64, now time.Time) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkNotLastAdmin(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Email and phone number are unique, so the placeholders are derived from the ID
+	query := `UPDATE users SET email = ?, phone_number = ?, first_name = 'Deleted', last_name = 'User', address = '',
+	suspension_reason = '', password = ?, token_version = token_version + 1, verified_at = NULL,
+	deletion_scheduled_at = NULL, deleted_at = ?
+	WHERE id = ?`
+	_, err = tx.ExecContext(ctx, query, anonymizedEmail(id), anonymizedPhoneNumber(id), unusablePassword, now.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []string{"user_roles", "api_keys", "refresh_tokens", "sessions", "password_resets", "totp_credentials", "recovery_codes"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = ?", id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// checkNotLastAdmin fails with ErrLastAdminDeletion when the user is an admin and no other admin would be left
+// once their account is deleted. Admins whose own deletion is scheduled do not count.
+func checkNotLastAdmin(ctx context.Context, tx *sql.Tx, id int64) error {
+	query := `
+	SELECT EXISTS (SELECT 1 FROM user_roles WHERE user_id = ? AND role = ?)
+	AND NOT EXISTS (
+		SELECT 1 FROM user_roles r JOIN users u ON u.id = r.user_id
+		WHERE r.role = ? AND r.user_id != ? AND u.deletion_scheduled_at IS NULL AND u.deleted_at IS NULL
+	)`
+	var last bool
+	if err := tx.QueryRowContext(ctx, query, id, RoleAdmin, RoleAdmin, id).Scan(&last); err != nil {
+		return err
+	}
+	if last {
+		return ErrLastAdminDeletion
+	}
+	return nil
+}
+
+func anonymizedEmail(id int64) string {
+	return fmt.Sprintf("deleted-%d@deleted.invalid", id)
+}
+
+func anonymizedPhoneNumber(id int64) string {
+	return fmt.Sprintf("deleted-%d", id)
+}
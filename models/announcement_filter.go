@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded or belongs to another ordering
+	ErrInvalidCursor = BadRequest("invalid_cursor", "invalid cursor")
+	// ErrInvalidSort is returned when the sort column or order is not one of the supported values
+	ErrInvalidSort = BadRequest("invalid_sort", "invalid sort")
+)
+
+// sortColumns maps the accepted sort values to their column, so user input never reaches the SQL text
+var sortColumns = map[string]string{
+	"id":          "id",
+	"start_date":  "start_date",
+	"end_date":    "end_date",
+	"create_date": "create_date",
+}
+
+// AnnouncementFilter narrows and orders an announcement listing; zero values mean "no constraint"
+type AnnouncementFilter struct {
+	Statuses    []Status
+	OwnerID     *int64
+	PublicOnly  bool  // hide announcements that are not active...
+	VisibleTo   int64 // ...except those owned by this user, when set
+	StartFrom   *time.Time
+	StartTo     *time.Time
+	EndFrom     *time.Time
+	EndTo       *time.Time
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        string // id, start_date, end_date or create_date; defaults to create_date
+	Order       string // asc or desc; defaults to desc
+	Limit       int
+	Cursor      string
+}
+
+// announcementCursor marks the last row of a page; Sort and Order pin it to the listing it came from
+type announcementCursor struct {
+	Sort  string    `json:"s"`
+	Order string    `json:"o"`
+	Time  time.Time `json:"t,omitempty"`
+	ID    int64     `json:"i"`
+}
+
+func (c announcementCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeAnnouncementCursor(cursor string) (announcementCursor, error) {
+	var c announcementCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// sortValue returns the value of the sort column for a, as used in the cursor
+func (c announcementCursor) sortValue(a Announcement) time.Time {
+	switch c.Sort {
+	case "start_date":
+		return a.StartDate
+	case "end_date":
+		return a.EndDate
+	case "create_date":
+		return a.CreateDate
+	}
+	return time.Time{}
+}
+
+// normalize fills in defaults and validates the sort and page size
+func (f *AnnouncementFilter) normalize() error {
+	if f.Sort == "" {
+		f.Sort = "create_date"
+	}
+	if _, ok := sortColumns[f.Sort]; !ok {
+		return ErrInvalidSort
+	}
+	f.Order = strings.ToLower(f.Order)
+	if f.Order == "" {
+		f.Order = "desc"
+	}
+	if f.Order != "asc" && f.Order != "desc" {
+		return ErrInvalidSort
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+	return nil
+}
+
+// timeRange bounds one of the date columns of an announcement
+type timeRange struct {
+	column   string
+	from, to *time.Time
+}
+
+func (f *AnnouncementFilter) timeRanges() []timeRange {
+	return []timeRange{
+		{"start_date", f.StartFrom, f.StartTo},
+		{"end_date", f.EndFrom, f.EndTo},
+		{"create_date", f.CreatedFrom, f.CreatedTo},
+	}
+}
+
+// decodeCursor reads the cursor of the filter, which must come from a listing with the same ordering
+func (f *AnnouncementFilter) decodeCursor() (announcementCursor, error) {
+	cursor, err := decodeAnnouncementCursor(f.Cursor)
+	if err != nil || cursor.Sort != f.Sort || cursor.Order != f.Order {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// page trims announcements, fetched one past the page size, to a page and returns the cursor of the next page,
+// which is empty on the last page
+func (f *AnnouncementFilter) page(announcements []Announcement) ([]Announcement, string) {
+	if len(announcements) <= f.Limit {
+		return announcements, ""
+	}
+	announcements = announcements[:f.Limit]
+	last := announcements[len(announcements)-1]
+	cursor := announcementCursor{Sort: f.Sort, Order: f.Order, ID: last.ID}
+	cursor.Time = cursor.sortValue(last)
+	return announcements, cursor.encode()
+}
@@ -0,0 +1,304 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// AnnouncementStore keeps announcements and the history of their statuses. Lookups of an announcement that does
+// not exist, or is soft-deleted, fail with sql.ErrNoRows whatever the implementation, and every method takes the
+// context of the request it serves so that abandoned requests stop querying.
+type AnnouncementStore interface {
+	// Create inserts the announcement as pending, and sets its ID and creation date
+	Create(ctx context.Context, a *Announcement) error
+	GetByID(ctx context.Context, id int64) (*Announcement, error)
+	// List returns one page of live announcements matching the filter and the cursor of the next page,
+	// which is empty on the last page
+	List(ctx context.Context, f AnnouncementFilter) ([]Announcement, string, error)
+	// ListDeleted returns the soft-deleted announcements that have not been purged yet, most recently deleted first
+	ListDeleted(ctx context.Context) ([]Announcement, error)
+	// ListByOwner returns every announcement of a user, soft-deleted ones included, oldest first
+	ListByOwner(ctx context.Context, ownerID int64) ([]Announcement, error)
+	// Update saves the editable fields, refusing the write with ErrAnnouncementLocked if the status moved past
+	// Pending/Declined meanwhile
+	Update(ctx context.Context, a *Announcement) error
+	// ChangeStatus moves an announcement from change.FromStatus to change.ToStatus and records the change, setting
+	// its ID. It fails with ErrStatusChanged when the announcement is no longer in FromStatus.
+	ChangeStatus(ctx context.Context, change *StatusChange) error
+	// StatusChanges returns the status history of an announcement, oldest first
+	StatusChanges(ctx context.Context, announcementID int64) ([]StatusChange, error)
+	// StatusChangesByOwner returns the status history of every announcement of a user, oldest first
+	StatusChangesByOwner(ctx context.Context, ownerID int64) ([]StatusChange, error)
+	// Delete soft-deletes an announcement
+	Delete(ctx context.Context, id int64) error
+	// Restore undoes a soft delete; it fails with sql.ErrNoRows if the announcement is not deleted
+	Restore(ctx context.Context, id int64) error
+	// PurgeDeleted permanently removes announcements soft-deleted before the cutoff, along with everything
+	// recorded about them, and returns how many it removed
+	PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// SQLAnnouncementStore keeps announcements in the announcements and status_changes tables of DB
+type SQLAnnouncementStore struct {
+	DB *sql.DB
+}
+
+func (s SQLAnnouncementStore) Create(ctx context.Context, a *Announcement) error {
+	// Times are stored in UTC so that they compare and sort correctly as text
+	a.CreateDate = time.Now().UTC()
+	a.StartDate = a.StartDate.UTC()
+	a.EndDate = a.EndDate.UTC()
+	a.Status = Pending
+	a.DeletedAt = nil
+
+	query := `INSERT INTO announcements (owner_id, status, text, start_date, end_date, create_date) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := s.DB.ExecContext(ctx, query, a.OwnerID, a.Status, a.Text, a.StartDate, a.EndDate, a.CreateDate)
+	if err != nil {
+		return err
+	}
+
+	a.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s SQLAnnouncementStore) GetByID(ctx context.Context, id int64) (*Announcement, error) {
+	query := `SELECT ` + announcementColumns + ` FROM announcements WHERE id = ? AND deleted_at IS NULL`
+	return scanAnnouncement(s.DB.QueryRowContext(ctx, query, id))
+}
+
+func (s SQLAnnouncementStore) List(ctx context.Context, f AnnouncementFilter) ([]Announcement, string, error) {
+	if err := f.normalize(); err != nil {
+		return nil, "", err
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	args := []any{}
+	where := func(condition string, values ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if len(f.Statuses) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(f.Statuses)), ", ")
+		values := make([]any, len(f.Statuses))
+		for i, s := range f.Statuses {
+			values[i] = s
+		}
+		where("status IN ("+placeholders+")", values...)
+	}
+	if f.OwnerID != nil {
+		where("owner_id = ?", *f.OwnerID)
+	}
+	if f.PublicOnly {
+		if f.VisibleTo != 0 {
+			where("(status = ? OR owner_id = ?)", Active, f.VisibleTo)
+		} else {
+			where("status = ?", Active)
+		}
+	}
+	for _, r := range f.timeRanges() {
+		if r.from != nil {
+			where(r.column+" >= ?", r.from.UTC())
+		}
+		if r.to != nil {
+			where(r.column+" <= ?", r.to.UTC())
+		}
+	}
+
+	column := sortColumns[f.Sort]
+	comparison := "<"
+	if f.Order == "asc" {
+		comparison = ">"
+	}
+	if f.Cursor != "" {
+		cursor, err := f.decodeCursor()
+		if err != nil {
+			return nil, "", err
+		}
+		// Keyset pagination: continue strictly after the last row, using id to break ties
+		if column == "id" {
+			where("id "+comparison+" ?", cursor.ID)
+		} else {
+			where("("+column+" "+comparison+" ? OR ("+column+" = ? AND id "+comparison+" ?))", cursor.Time, cursor.Time, cursor.ID)
+		}
+	}
+
+	order := strings.ToUpper(f.Order)
+	query := `SELECT ` + announcementColumns + ` FROM announcements WHERE ` + strings.Join(conditions, " AND ")
+	if column == "id" {
+		query += ` ORDER BY id ` + order
+	} else {
+		query += ` ORDER BY ` + column + ` ` + order + `, id ` + order
+	}
+	query += ` LIMIT ?`
+	args = append(args, f.Limit+1)
+
+	announcements, err := s.queryAnnouncements(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	announcements, nextCursor := f.page(announcements)
+	return announcements, nextCursor, nil
+}
+
+func (s SQLAnnouncementStore) ListDeleted(ctx context.Context) ([]Announcement, error) {
+	query := `SELECT ` + announcementColumns + ` FROM announcements WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`
+	return s.queryAnnouncements(ctx, query)
+}
+
+func (s SQLAnnouncementStore) ListByOwner(ctx context.Context, ownerID int64) ([]Announcement, error) {
+	query := `SELECT ` + announcementColumns + ` FROM announcements WHERE owner_id = ? ORDER BY id`
+	return s.queryAnnouncements(ctx, query, ownerID)
+}
+
+func (s SQLAnnouncementStore) queryAnnouncements(ctx context.Context, query string, args ...any) ([]Announcement, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	announcements := []Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, *a)
+	}
+
+	return announcements, rows.Err()
+}
+
+func (s SQLAnnouncementStore) Update(ctx context.Context, a *Announcement) error {
+	a.StartDate = a.StartDate.UTC()
+	a.EndDate = a.EndDate.UTC()
+	query := `UPDATE announcements SET text = ?, start_date = ?, end_date = ? WHERE id = ? AND deleted_at IS NULL AND status IN (?, ?)`
+	err := affectedOne(s.DB.ExecContext(ctx, query, a.Text, a.StartDate, a.EndDate, a.ID, Pending, Declined))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAnnouncementLocked
+	}
+	return err
+}
+
+func (s SQLAnnouncementStore) ChangeStatus(ctx context.Context, change *StatusChange) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Only move from the status that was validated against, so concurrent changes cannot skip a step
+	query := `UPDATE announcements SET status = ? WHERE id = ? AND status = ? AND deleted_at IS NULL`
+	err = affectedOne(tx.ExecContext(ctx, query, change.ToStatus, change.AnnouncementID, change.FromStatus))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrStatusChanged
+	}
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO status_changes (announcement_id, from_status, to_status, changed_by, reason, changed_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, change.AnnouncementID, change.FromStatus, change.ToStatus, actor(change.ChangedBy), change.Reason, change.ChangedAt)
+	if err != nil {
+		return err
+	}
+	change.ID, err = result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// statusChangeColumns lists the columns queryStatusChanges expects, in order
+const statusChangeColumns = `status_changes.id, announcement_id, from_status, to_status, changed_by, reason, changed_at`
+
+func (s SQLAnnouncementStore) StatusChanges(ctx context.Context, announcementID int64) ([]StatusChange, error) {
+	query := `SELECT ` + statusChangeColumns + ` FROM status_changes WHERE announcement_id = ? ORDER BY changed_at, id`
+	return s.queryStatusChanges(ctx, query, announcementID)
+}
+
+func (s SQLAnnouncementStore) StatusChangesByOwner(ctx context.Context, ownerID int64) ([]StatusChange, error) {
+	query := `SELECT ` + statusChangeColumns + ` FROM status_changes
+	JOIN announcements ON announcements.id = status_changes.announcement_id
+	WHERE announcements.owner_id = ? ORDER BY changed_at, status_changes.id`
+	return s.queryStatusChanges(ctx, query, ownerID)
+}
+
+func (s SQLAnnouncementStore) queryStatusChanges(ctx context.Context, query string, args ...any) ([]StatusChange, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []StatusChange{}
+	for rows.Next() {
+		var c StatusChange
+		var changedBy sql.NullInt64
+		err := rows.Scan(&c.ID, &c.AnnouncementID, &c.FromStatus, &c.ToStatus, &changedBy, &c.Reason, &c.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		// NULL, for changes the application made, reads as SystemUserID
+		c.ChangedBy = changedBy.Int64
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+func (s SQLAnnouncementStore) Delete(ctx context.Context, id int64) error {
+	query := `UPDATE announcements SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	return affectedOne(s.DB.ExecContext(ctx, query, time.Now().UTC(), id))
+}
+
+func (s SQLAnnouncementStore) Restore(ctx context.Context, id int64) error {
+	query := `UPDATE announcements SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+	return affectedOne(s.DB.ExecContext(ctx, query, id))
+}
+
+// PurgeDeleted removes the status history and flags of the purged announcements too
+func (s SQLAnnouncementStore) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	cutoff = cutoff.UTC()
+	expired := `SELECT id FROM announcements WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+	_, err = tx.ExecContext(ctx, `DELETE FROM status_changes WHERE announcement_id IN (`+expired+`)`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM flags WHERE announcement_id IN (`+expired+`)`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM announcements WHERE id IN (`+expired+`)`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
+
+// announcementColumns lists the columns scanAnnouncement expects, in order
+const announcementColumns = `id, owner_id, status, text, start_date, end_date, create_date, deleted_at`
+
+func scanAnnouncement(row rowScanner) (*Announcement, error) {
+	var a Announcement
+	err := row.Scan(&a.ID, &a.OwnerID, &a.Status, &a.Text, &a.StartDate, &a.EndDate, &a.CreateDate, &a.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -1,84 +1,88 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
 	"time"
+)
 
-	"github.com/ngirimana/AnnounceIT/db"
+var (
+	// ErrAnnouncementLocked is returned when an announcement has moved past the editable statuses
+	ErrAnnouncementLocked = Conflict("announcement_locked", "Announcement can no longer be edited")
+	// ErrInvalidDateRange is returned when an announcement would end before it starts
+	ErrInvalidDateRange = errors.New("end_date must be after start_date")
 )
 
-type Status int
+type Announcement struct {
+	ID         int64      `json:"id"`
+	OwnerID    int64      `json:"owner_id"`
+	Status     Status     `json:"status" swaggertype:"string" enums:"pending,accepted,declined,active,deactivated"`
+	Text       string     `json:"text" binding:"required,notblank,announcement_length"`
+	StartDate  time.Time  `json:"start_date" binding:"required"`
+	EndDate    time.Time  `json:"end_date" binding:"required,after=StartDate"`
+	CreateDate time.Time  `json:"create_date"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
 
-// Define enum constants using iota
-const (
-	Pending     Status = iota // 0
-	Accepted    Status = iota // 1
-	Declined    Status = iota // 2
-	Active      Status = iota // 3
-	Deactivated Status = iota // 4
-)
+// AnnouncementUpdate holds the fields an owner may change; nil fields are left untouched
+type AnnouncementUpdate struct {
+	Text      *string    `json:"text" binding:"omitempty,notblank,announcement_length"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
 
-func (s Status) String() string {
-	return [...]string{"Pending", "Accepted", "Declined", "Active", "Deactivated"}[s]
+// CheckDates reports ErrInvalidDateRange unless the announcement ends after it starts
+func (a *Announcement) CheckDates() error {
+	if !a.EndDate.After(a.StartDate) {
+		return ErrInvalidDateRange
+	}
+	return nil
 }
 
-type Announcement struct {
-	ID         int64     `json:"id"`
-	OwnerID    int64     `json:"owner_id"`
-	Status     Status    `json:"status"`
-	Text       string    `json:"text"`
-	StartDate  time.Time `json:"start_date"`
-	EndDate    time.Time `json:"end_date"`
-	CreateDate time.Time `json:"create_date"`
+// IsComplete reports whether every editable field is set, as required for a full replacement
+func (u AnnouncementUpdate) IsComplete() bool {
+	return u.Text != nil && u.StartDate != nil && u.EndDate != nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func (a *Announcement) Create() error {
-	query := `INSERT INTO announcements (owner_id, status, text, start_date, end_date, create_date) VALUES (?, ?, ?, ?, ?, ?)`
-	stmt, err := db.DB.Prepare(query)
+// affectedOne turns the outcome of a write meant for a single row into sql.ErrNoRows when it changed nothing
+func affectedOne(result sql.Result, err error) error {
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-	a.CreateDate = time.Now()
-	a.Status = Pending
-	result, err := stmt.Exec(a.OwnerID, a.Status, a.Text, a.StartDate, a.EndDate, a.CreateDate)
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-
-	a.ID, err = result.LastInsertId()
-	return err
-}
-
-func GetAnnouncements() ([]Announcement, error) {
-	query := `SELECT * FROM announcements`
-	rows, err := db.DB.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	announcements := []Announcement{}
-	for rows.Next() {
-		var a Announcement
-		err := rows.Scan(&a.ID, &a.OwnerID, &a.Status, &a.Text, &a.StartDate, &a.EndDate, &a.CreateDate)
-		if err != nil {
-			return nil, err
-		}
-		announcements = append(announcements, a)
+	if affected == 0 {
+		return sql.ErrNoRows
 	}
+	return nil
+}
 
-	return announcements, nil
+// IsVisibleTo reports whether the announcement may be shown to the given user; anonymous callers pass 0
+func (a *Announcement) IsVisibleTo(userID int64, canReadAll bool) bool {
+	return a.Status == Active || canReadAll || (userID != 0 && a.OwnerID == userID)
 }
 
-func GetAnnouncementByID(id int64) (*Announcement, error) {
-	query := `SELECT * FROM announcements WHERE id = ?`
-	row := db.DB.QueryRow(query, id)
+// IsEditable reports whether the owner may still change the announcement
+func (a *Announcement) IsEditable() bool {
+	return a.Status == Pending || a.Status == Declined
+}
 
-	var a Announcement
-	err := row.Scan(&a.ID, &a.OwnerID, &a.Status, &a.Text, &a.StartDate, &a.EndDate, &a.CreateDate)
-	if err != nil {
-		return nil, err
+// Apply copies the fields that are set in the update onto the announcement
+func (a *Announcement) Apply(u AnnouncementUpdate) {
+	if u.Text != nil {
+		a.Text = *u.Text
+	}
+	if u.StartDate != nil {
+		a.StartDate = *u.StartDate
+	}
+	if u.EndDate != nil {
+		a.EndDate = *u.EndDate
 	}
-
-	return &a, nil
 }
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/helpers"
+)
+
+// ErrInvalidAPIKey is returned when an API key is unknown, expired or revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// apiKeyPrefix marks AnnounceIT API keys, so that leaked keys are easy to recognise
+const apiKeyPrefix = "aik_"
+
+// APIKey lets an integration act for its owner, limited to its scopes; the key itself is only shown when created
+type APIKey struct {
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []Permission `json:"scopes" swaggertype:"array,string"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type APIKeyRequest struct {
+	Name      string       `json:"name" binding:"required"`
+	Scopes    []Permission `json:"scopes" binding:"required,min=1" swaggertype:"array,string"`
+	ExpiresAt *time.Time   `json:"expires_at"`
+}
+
+// APIKeyStore keeps the API keys of users. Only the hash of a key is stored, next to its prefix in clear.
+type APIKeyStore interface {
+	// Create stores the key under the hash of the full key, and sets its ID
+	Create(ctx context.Context, key *APIKey, keyHash string) error
+	// List returns the keys of the user that have not been revoked, newest first
+	List(ctx context.Context, userId int64) ([]APIKey, error)
+	// Revoke stops one of the user's keys from working; it fails with sql.ErrNoRows for keys of other users
+	Revoke(ctx context.Context, userId, id int64) error
+	// GetByPrefix returns the key with the prefix that has not been revoked, and its hash. It fails with
+	// sql.ErrNoRows when there is none.
+	GetByPrefix(ctx context.Context, prefix string) (*APIKey, string, error)
+	// MarkUsed records when the key was last used
+	MarkUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+// SQLAPIKeyStore keeps API keys in the api_keys table of DB
+type SQLAPIKeyStore struct {
+	DB *sql.DB
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at`
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var k APIKey
+	var scopes string
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopes, &k.ExpiresAt, &k.LastUsedAt, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range strings.Split(scopes, ",") {
+		k.Scopes = append(k.Scopes, Permission(scope))
+	}
+	return &k, nil
+}
+
+// CreateAPIKey issues a key for the user and returns it along with the full key, which is not stored
+func CreateAPIKey(ctx context.Context, keys APIKeyStore, userId int64, name string, scopes []Permission, expiresAt *time.Time) (*APIKey, string, error) {
+	// The prefix is stored in clear to find the key; the rest is only stored hashed
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, "", err
+	}
+	secret, err := helpers.GenerateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	key := APIKey{
+		UserID:    userId,
+		Name:      name,
+		Prefix:    hex.EncodeToString(prefixBytes),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if expiresAt != nil {
+		utc := expiresAt.UTC()
+		key.ExpiresAt = &utc
+	}
+	fullKey := apiKeyPrefix + key.Prefix + "_" + secret
+
+	if err := keys.Create(ctx, &key, helpers.HashToken(fullKey)); err != nil {
+		return nil, "", err
+	}
+	return &key, fullKey, nil
+}
+
+// AuthenticateAPIKey returns the key matching fullKey and records that it was used
+func AuthenticateAPIKey(ctx context.Context, keys APIKeyStore, fullKey string) (*APIKey, error) {
+	rest, ok := strings.CutPrefix(fullKey, apiKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, keyHash, err := keys.GetByPrefix(ctx, prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(keyHash), []byte(helpers.HashToken(fullKey))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	now := time.Now().UTC()
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := keys.MarkUsed(ctx, key.ID, now); err != nil {
+		return nil, err
+	}
+	key.LastUsedAt = &now
+	return key, nil
+}
+
+func (s SQLAPIKeyStore) Create(ctx context.Context, key *APIKey, keyHash string) error {
+	scopeNames := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopeNames[i] = string(scope)
+	}
+	query := `
+	INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.DB.ExecContext(ctx, query, key.UserID, key.Name, key.Prefix, keyHash, strings.Join(scopeNames, ","), key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		return err
+	}
+	key.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s SQLAPIKeyStore) List(ctx context.Context, userId int64) ([]APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = ? AND revoked_at IS NULL ORDER BY id DESC`
+	rows, err := s.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func (s SQLAPIKeyStore) Revoke(ctx context.Context, userId, id int64) error {
+	query := `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+	return affectedOne(s.DB.ExecContext(ctx, query, time.Now().UTC(), id, userId))
+}
+
+func (s SQLAPIKeyStore) GetByPrefix(ctx context.Context, prefix string) (*APIKey, string, error) {
+	var id int64
+	var keyHash string
+	query := "SELECT id, key_hash FROM api_keys WHERE prefix = ? AND revoked_at IS NULL"
+	err := s.DB.QueryRowContext(ctx, query, prefix).Scan(&id, &keyHash)
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := scanAPIKey(s.DB.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+	if err != nil {
+		return nil, "", err
+	}
+	return key, keyHash, nil
+}
+
+func (s SQLAPIKeyStore) MarkUsed(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", at.UTC(), id)
+	return err
+}
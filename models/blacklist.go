@@ -0,0 +1,159 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrNotBlacklisted is returned when removing a user who is not on the blacklist
+var ErrNotBlacklisted = NotFound("not_blacklisted", "User is not blacklisted")
+
+type BlacklistAction string
+
+const (
+	BlacklistAdded   BlacklistAction = "added"
+	BlacklistRemoved BlacklistAction = "removed"
+)
+
+// BlacklistChange records a user being put on or taken off the blacklist; the latest change for a user is
+// their current state
+type BlacklistChange struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id"`
+	Action    BlacklistAction `json:"action" enums:"added,removed"`
+	Reason    string          `json:"reason"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	ChangedBy int64           `json:"changed_by"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+type BlacklistRequest struct {
+	Reason    string     `json:"reason" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UnblacklistRequest struct {
+	Reason string `json:"reason"`
+}
+
+// BlacklistStore keeps the history of the blacklist, from which the current state of each user follows
+type BlacklistStore interface {
+	// Latest returns the most recent change for the user, failing with sql.ErrNoRows when there is none
+	Latest(ctx context.Context, userId int64) (*BlacklistChange, error)
+	// Record appends the change to the history, and sets its ID
+	Record(ctx context.Context, change *BlacklistChange) error
+	// Changes lists blacklist changes, newest first, for one user or for everyone when userId is 0
+	Changes(ctx context.Context, userId int64) ([]BlacklistChange, error)
+}
+
+// SQLBlacklistStore keeps the history in the blacklist_changes table of DB
+type SQLBlacklistStore struct {
+	DB *sql.DB
+}
+
+const blacklistColumns = `id, user_id, action, reason, expires_at, changed_by, changed_at`
+
+func scanBlacklistChange(row rowScanner) (*BlacklistChange, error) {
+	var c BlacklistChange
+	err := row.Scan(&c.ID, &c.UserID, &c.Action, &c.Reason, &c.ExpiresAt, &c.ChangedBy, &c.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// IsActive reports whether the change currently keeps the user on the blacklist
+func (c *BlacklistChange) IsActive() bool {
+	return c.Action == BlacklistAdded && (c.ExpiresAt == nil || c.ExpiresAt.After(time.Now()))
+}
+
+// GetActiveBlacklisting returns the entry that currently blacklists the user, or nil if they are not blacklisted
+func GetActiveBlacklisting(ctx context.Context, blacklist BlacklistStore, userId int64) (*BlacklistChange, error) {
+	change, err := blacklist.Latest(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !change.IsActive() {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// BlacklistUser stops the user from creating or editing announcements until expiresAt, or indefinitely when it is nil
+func BlacklistUser(ctx context.Context, blacklist BlacklistStore, userId, changedBy int64, reason string, expiresAt *time.Time) (*BlacklistChange, error) {
+	if expiresAt != nil {
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+	return recordBlacklistChange(ctx, blacklist, BlacklistChange{
+		UserID:    userId,
+		Action:    BlacklistAdded,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+		ChangedBy: changedBy,
+	})
+}
+
+// UnblacklistUser takes the user off the blacklist
+func UnblacklistUser(ctx context.Context, blacklist BlacklistStore, userId, changedBy int64, reason string) (*BlacklistChange, error) {
+	active, err := GetActiveBlacklisting(ctx, blacklist, userId)
+	if err != nil {
+		return nil, err
+	}
+	if active == nil {
+		return nil, ErrNotBlacklisted
+	}
+	return recordBlacklistChange(ctx, blacklist, BlacklistChange{
+		UserID:    userId,
+		Action:    BlacklistRemoved,
+		Reason:    reason,
+		ChangedBy: changedBy,
+	})
+}
+
+func recordBlacklistChange(ctx context.Context, blacklist BlacklistStore, change BlacklistChange) (*BlacklistChange, error) {
+	change.ChangedAt = time.Now().UTC()
+	if err := blacklist.Record(ctx, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (s SQLBlacklistStore) Latest(ctx context.Context, userId int64) (*BlacklistChange, error) {
+	query := `SELECT ` + blacklistColumns + ` FROM blacklist_changes WHERE user_id = ? ORDER BY id DESC LIMIT 1`
+	return scanBlacklistChange(s.DB.QueryRowContext(ctx, query, userId))
+}
+
+func (s SQLBlacklistStore) Record(ctx context.Context, change *BlacklistChange) error {
+	query := `INSERT INTO blacklist_changes (user_id, action, reason, expires_at, changed_by, changed_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := s.DB.ExecContext(ctx, query, change.UserID, change.Action, change.Reason, change.ExpiresAt, change.ChangedBy, change.ChangedAt)
+	if err != nil {
+		return err
+	}
+	change.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s SQLBlacklistStore) Changes(ctx context.Context, userId int64) ([]BlacklistChange, error) {
+	query := `SELECT ` + blacklistColumns + ` FROM blacklist_changes WHERE (? = 0 OR user_id = ?) ORDER BY id DESC`
+	rows, err := s.DB.QueryContext(ctx, query, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []BlacklistChange{}
+	for rows.Next() {
+		c, err := scanBlacklistChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *c)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,82 @@
+package models
+
+// Kind classifies what went wrong from the client's point of view, which decides the HTTP status of the response
+type Kind int
+
+const (
+	// KindInternal is a failure the client cannot do anything about; its detail is never shown
+	KindInternal Kind = iota
+	KindBadRequest
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindTooManyRequests
+)
+
+// Error is a domain error whose message is safe to show to clients. Code identifies it for programs and
+// does not change when the message is reworded.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Fields lists the failing fields of a KindValidation error
+	Fields []FieldError
+	// Extra holds further members for the response, such as the statuses a rejected transition could go to
+	Extra map[string]any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// With returns a copy of the error carrying one more member for the response
+func (e *Error) With(key string, value any) *Error {
+	copied := *e
+	copied.Extra = make(map[string]any, len(e.Extra)+1)
+	for k, v := range e.Extra {
+		copied.Extra[k] = v
+	}
+	copied.Extra[key] = value
+	return &copied
+}
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// BadRequest is for requests that cannot be understood, such as malformed JSON or an unparsable ID
+func BadRequest(code, message string) *Error {
+	return newError(KindBadRequest, code, message)
+}
+
+// Unauthorized is for callers who are not authenticated, or whose credentials are wrong
+func Unauthorized(code, message string) *Error {
+	return newError(KindUnauthorized, code, message)
+}
+
+// Forbidden is for authenticated callers who may not do what they asked
+func Forbidden(code, message string) *Error {
+	return newError(KindForbidden, code, message)
+}
+
+// NotFound is for resources that do not exist, or that the caller may not know about
+func NotFound(code, message string) *Error {
+	return newError(KindNotFound, code, message)
+}
+
+// Conflict is for requests that clash with the current state of a resource
+func Conflict(code, message string) *Error {
+	return newError(KindConflict, code, message)
+}
+
+// TooManyRequests is for callers who have to wait before trying again
+func TooManyRequests(code, message string) *Error {
+	return newError(KindTooManyRequests, code, message)
+}
+
+// Invalid is for request bodies that parse but have fields that fail validation
+func Invalid(fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: "Validation failed", Fields: fields}
+}
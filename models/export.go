@@ -0,0 +1,135 @@
+package models
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UserExport is everything stored about a user, as handed to them on request
+type UserExport struct {
+	ExportedAt    time.Time      `json:"exported_at"`
+	Profile       User           `json:"profile"`
+	Announcements []Announcement `json:"announcements"`
+	// Flags are the flags the user filed on announcements
+	Flags []Flag `json:"flags"`
+	// StatusHistory covers every announcement of the user
+	StatusHistory []StatusChange `json:"status_history"`
+}
+
+// ExportUser gathers the personal data of a user, including announcements they have deleted but that are not purged yet
+func ExportUser(ctx context.Context, users UserStore, announcements AnnouncementStore, flags FlagStore, userId int64) (*UserExport, error) {
+	user, err := users.GetByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	export := UserExport{ExportedAt: time.Now().UTC(), Profile: *user}
+
+	export.Announcements, err = announcements.ListByOwner(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	export.Flags, err = flags.ListByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	export.StatusHistory, err = announcements.StatusChangesByOwner(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// WriteArchive writes the export as a zip archive holding export.json and one CSV file per kind of record
+func (e *UserExport) WriteArchive(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create("export.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(e); err != nil {
+		return err
+	}
+
+	profile := e.Profile
+	err = writeCSV(archive, "profile.csv",
+		[]string{"id", "email", "first_name", "last_name", "phone_number", "address", "roles", "verified_at"},
+		[][]string{{formatID(profile.ID), profile.Email, profile.FirstName, profile.LastName, profile.PhoneNumber,
+			profile.Address, strings.Join(profile.RoleNames(), " "), formatOptionalTime(profile.VerifiedAt)}})
+	if err != nil {
+		return err
+	}
+
+	records := make([][]string, 0, len(e.Announcements))
+	for _, a := range e.Announcements {
+		records = append(records, []string{formatID(a.ID), a.Status.String(), a.Text, formatTime(a.StartDate),
+			formatTime(a.EndDate), formatTime(a.CreateDate), formatOptionalTime(a.DeletedAt)})
+	}
+	err = writeCSV(archive, "announcements.csv",
+		[]string{"id", "status", "text", "start_date", "end_date", "create_date", "deleted_at"}, records)
+	if err != nil {
+		return err
+	}
+
+	records = make([][]string, 0, len(e.Flags))
+	for _, f := range e.Flags {
+		records = append(records, []string{formatID(f.ID), formatID(f.AnnouncementID), string(f.Reason), f.Description,
+			string(f.Status), formatTime(f.CreatedOn), formatOptionalTime(f.ResolvedAt)})
+	}
+	err = writeCSV(archive, "flags.csv",
+		[]string{"id", "announcement_id", "reason", "description", "status", "created_on", "resolved_at"}, records)
+	if err != nil {
+		return err
+	}
+
+	records = make([][]string, 0, len(e.StatusHistory))
+	for _, c := range e.StatusHistory {
+		records = append(records, []string{formatID(c.ID), formatID(c.AnnouncementID), c.FromStatus.String(),
+			c.ToStatus.String(), c.Reason, formatTime(c.ChangedAt)})
+	}
+	err = writeCSV(archive, "status_history.csv",
+		[]string{"id", "announcement_id", "from_status", "to_status", "reason", "changed_at"}, records)
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func writeCSV(archive *zip.Writer, name string, header []string, records [][]string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
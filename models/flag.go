@@ -0,0 +1,262 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SystemUserID stands for the application as the author of changes it makes on its own. It is no user, so it is
+// stored as NULL.
+const SystemUserID int64 = 0
+
+// actor is the value stored for the author of a change
+func actor(userId int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: userId, Valid: userId != SystemUserID}
+}
+
+var (
+	// ErrAlreadyFlagged is returned when the user already flagged the announcement
+	ErrAlreadyFlagged = Conflict("already_flagged", "Announcement already flagged by this user")
+	// ErrInvalidFlagReason is returned for reasons outside the fixed vocabulary
+	ErrInvalidFlagReason = errors.New("invalid flag reason")
+	// ErrFlagNotOpen is returned when resolving a flag that was already handled
+	ErrFlagNotOpen = Conflict("flag_not_open", "Flag is not open")
+	// ErrInvalidFlagResolution is returned when a flag is closed with a status other than resolved or dismissed
+	ErrInvalidFlagResolution = BadRequest("invalid_flag_resolution", "Flags can only be resolved or dismissed")
+)
+
+type FlagReason string
+
+const (
+	FlagSexist      FlagReason = "sexist"
+	FlagRacist      FlagReason = "racist"
+	FlagBadLanguage FlagReason = "bad_language"
+	FlagSpam        FlagReason = "spam"
+	FlagOther       FlagReason = "other"
+)
+
+// FlagReasons lists the reasons a user may give when flagging an announcement
+var FlagReasons = []FlagReason{FlagSexist, FlagRacist, FlagBadLanguage, FlagSpam, FlagOther}
+
+func (r FlagReason) IsValid() bool {
+	for _, reason := range FlagReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+type FlagStatus string
+
+const (
+	FlagOpen      FlagStatus = "open"
+	FlagResolved  FlagStatus = "resolved"
+	FlagDismissed FlagStatus = "dismissed"
+)
+
+type Flag struct {
+	ID             int64      `json:"id"`
+	AnnouncementID int64      `json:"announcement_id"`
+	UserID         int64      `json:"user_id"`
+	Reason         FlagReason `json:"reason" enums:"sexist,racist,bad_language,spam,other"`
+	Description    string     `json:"description"`
+	Status         FlagStatus `json:"status" enums:"open,resolved,dismissed"`
+	CreatedOn      time.Time  `json:"created_on"`
+	ResolvedBy     *int64     `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+type FlagRequest struct {
+	Reason      FlagReason `json:"reason" binding:"required" enums:"sexist,racist,bad_language,spam,other"`
+	Description string     `json:"description"`
+}
+
+type FlagResolutionRequest struct {
+	Status FlagStatus `json:"status" binding:"required" enums:"resolved,dismissed"`
+}
+
+// FlagCount summarises the flags of one announcement
+type FlagCount struct {
+	AnnouncementID int64 `json:"announcement_id"`
+	Open           int   `json:"open"`
+	Total          int   `json:"total"`
+}
+
+// FlagStore keeps the flags users file on announcements. Looking up a flag that does not exist fails with sql.ErrNoRows.
+type FlagStore interface {
+	// Create stores the flag and sets its ID; it fails with ErrAlreadyFlagged when the user flagged the announcement before
+	Create(ctx context.Context, f *Flag) error
+	// Resolve closes an open flag with the status, failing with ErrFlagNotOpen when it was handled already
+	Resolve(ctx context.Context, id int64, status FlagStatus, resolvedBy int64, at time.Time) error
+	GetByID(ctx context.Context, id int64) (*Flag, error)
+	// List lists flags, newest first, optionally narrowed to one status and/or one announcement (0 for all)
+	List(ctx context.Context, status FlagStatus, announcementID int64) ([]Flag, error)
+	// ListByUser lists the flags a user filed, newest first
+	ListByUser(ctx context.Context, userID int64) ([]Flag, error)
+	// ListAgainstUser lists the flags filed on announcements of the user, newest first
+	ListAgainstUser(ctx context.Context, userID int64) ([]Flag, error)
+	// CountOpen returns how many unhandled flags an announcement has
+	CountOpen(ctx context.Context, announcementID int64) (int, error)
+	// Counts returns the flag counts of every flagged announcement, most open flags first
+	Counts(ctx context.Context) ([]FlagCount, error)
+}
+
+// SQLFlagStore keeps flags in the flags table of DB
+type SQLFlagStore struct {
+	DB *sql.DB
+}
+
+const flagColumns = `id, announcement_id, user_id, reason, description, status, created_on, resolved_by, resolved_at`
+
+func scanFlag(row rowScanner) (*Flag, error) {
+	var f Flag
+	err := row.Scan(&f.ID, &f.AnnouncementID, &f.UserID, &f.Reason, &f.Description, &f.Status, &f.CreatedOn, &f.ResolvedBy, &f.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Create files the flag; a user may flag each announcement only once
+func (f *Flag) Create(ctx context.Context, flags FlagStore) error {
+	if !f.Reason.IsValid() {
+		return ErrInvalidFlagReason
+	}
+
+	f.Status = FlagOpen
+	f.CreatedOn = time.Now().UTC()
+	f.ResolvedBy = nil
+	f.ResolvedAt = nil
+	return flags.Create(ctx, f)
+}
+
+// Resolve closes an open flag as resolved or dismissed
+func (f *Flag) Resolve(ctx context.Context, flags FlagStore, status FlagStatus, resolvedBy int64) error {
+	if status != FlagResolved && status != FlagDismissed {
+		return ErrInvalidFlagResolution
+	}
+
+	now := time.Now().UTC()
+	if err := flags.Resolve(ctx, f.ID, status, resolvedBy, now); err != nil {
+		return err
+	}
+	f.Status = status
+	f.ResolvedBy = &resolvedBy
+	f.ResolvedAt = &now
+	return nil
+}
+
+func (s SQLFlagStore) Create(ctx context.Context, f *Flag) error {
+	query := `INSERT INTO flags (announcement_id, user_id, reason, description, status, created_on) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := s.DB.ExecContext(ctx, query, f.AnnouncementID, f.UserID, f.Reason, f.Description, f.Status, f.CreatedOn)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrAlreadyFlagged
+	}
+	if err != nil {
+		return err
+	}
+
+	f.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s SQLFlagStore) Resolve(ctx context.Context, id int64, status FlagStatus, resolvedBy int64, at time.Time) error {
+	query := `UPDATE flags SET status = ?, resolved_by = ?, resolved_at = ? WHERE id = ? AND status = ?`
+	err := affectedOne(s.DB.ExecContext(ctx, query, status, resolvedBy, at.UTC(), id, FlagOpen))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrFlagNotOpen
+	}
+	return err
+}
+
+func (s SQLFlagStore) GetByID(ctx context.Context, id int64) (*Flag, error) {
+	query := `SELECT ` + flagColumns + ` FROM flags WHERE id = ?`
+	return scanFlag(s.DB.QueryRowContext(ctx, query, id))
+}
+
+func (s SQLFlagStore) List(ctx context.Context, status FlagStatus, announcementID int64) ([]Flag, error) {
+	query := `SELECT ` + flagColumns + ` FROM flags WHERE (? = '' OR status = ?) AND (? = 0 OR announcement_id = ?) ORDER BY created_on DESC, id DESC`
+	return s.query(ctx, query, status, status, announcementID, announcementID)
+}
+
+func (s SQLFlagStore) ListByUser(ctx context.Context, userID int64) ([]Flag, error) {
+	query := `SELECT ` + flagColumns + ` FROM flags WHERE user_id = ? ORDER BY created_on DESC, id DESC`
+	return s.query(ctx, query, userID)
+}
+
+func (s SQLFlagStore) ListAgainstUser(ctx context.Context, userID int64) ([]Flag, error) {
+	query := `SELECT ` + flagColumns + ` FROM flags WHERE announcement_id IN (SELECT id FROM announcements WHERE owner_id = ?) ORDER BY created_on DESC, id DESC`
+	return s.query(ctx, query, userID)
+}
+
+func (s SQLFlagStore) query(ctx context.Context, query string, args ...any) ([]Flag, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []Flag{}
+	for rows.Next() {
+		f, err := scanFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, *f)
+	}
+	return flags, rows.Err()
+}
+
+func (s SQLFlagStore) CountOpen(ctx context.Context, announcementID int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM flags WHERE announcement_id = ? AND status = ?`
+	err := s.DB.QueryRowContext(ctx, query, announcementID, FlagOpen).Scan(&count)
+	return count, err
+}
+
+func (s SQLFlagStore) Counts(ctx context.Context) ([]FlagCount, error) {
+	query := `SELECT announcement_id, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), COUNT(*) FROM flags GROUP BY announcement_id ORDER BY 2 DESC, 3 DESC, announcement_id`
+	rows, err := s.DB.QueryContext(ctx, query, FlagOpen)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []FlagCount{}
+	for rows.Next() {
+		var c FlagCount
+		if err := rows.Scan(&c.AnnouncementID, &c.Open, &c.Total); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// DeactivateIfFlagged takes an active announcement off the air for review once it has threshold open flags;
+// it reports whether the announcement was deactivated
+func (a *Announcement) DeactivateIfFlagged(ctx context.Context, announcements AnnouncementStore, flags FlagStore, threshold int) (bool, error) {
+	if a.Status != Active || threshold <= 0 {
+		return false, nil
+	}
+
+	count, err := flags.CountOpen(ctx, a.ID)
+	if err != nil || count < threshold {
+		return false, err
+	}
+
+	reason := fmt.Sprintf("Automatically deactivated pending review after %d flags", count)
+	_, err = a.ChangeStatus(ctx, announcements, Deactivated, SystemUserID, reason)
+	if errors.Is(err, ErrStatusChanged) {
+		// Someone else changed the status meanwhile; their decision stands
+		return false, nil
+	}
+	return err == nil, err
+}
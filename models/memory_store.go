@@ -0,0 +1,1310 @@
+package models
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/helpers"
+)
+
+// MemoryUserStore keeps users in the process, so that tests of the handlers need no database and can run in parallel.
+// Sessions, API keys and the like are kept in memory stores of their own, so suspending or anonymizing a user
+// bumps their token version, which revokes their JWTs and refresh tokens, but leaves their API keys alone.
+type MemoryUserStore struct {
+	mu     sync.Mutex
+	users  map[int64]*memoryUser
+	lastID int64
+}
+
+type memoryUser struct {
+	User
+	passwordHash string
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: map[int64]*memoryUser{}}
+}
+
+func (s *MemoryUserStore) Create(ctx context.Context, u *User, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.users {
+		if other.Email == u.Email {
+			return ErrEmailTaken
+		}
+		if other.PhoneNumber == u.PhoneNumber {
+			return ErrPhoneNumberTaken
+		}
+	}
+
+	s.lastID++
+	u.ID = s.lastID
+	u.Roles = []Role{RoleAdvertiser}
+	u.TokenVersion = 0
+	u.VerifiedAt, u.SuspendedAt, u.SuspensionReason = nil, nil, ""
+	u.DeletionScheduledAt, u.DeletedAt = nil, nil
+
+	stored := &memoryUser{User: *u, passwordHash: passwordHash}
+	stored.Password = ""
+	stored.Roles = slices.Clone(u.Roles)
+	s.users[u.ID] = stored
+	return nil
+}
+
+func (s *MemoryUserStore) GetByID(ctx context.Context, id int64) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return stored.copy(), nil
+}
+
+func (s *MemoryUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.users {
+		if stored.Email == email {
+			return stored.copy(), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// copy returns the user without anything a caller could use to change the stored one
+func (u *memoryUser) copy() *User {
+	user := u.User
+	user.Roles = slices.Clone(u.Roles)
+	return &user
+}
+
+func (s *MemoryUserStore) PasswordHash(ctx context.Context, id int64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return stored.passwordHash, nil
+}
+
+func (s *MemoryUserStore) ReplacePasswordHash(ctx context.Context, id int64, oldHash, newHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.users[id]; ok && stored.passwordHash == oldHash {
+		stored.passwordHash = newHash
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) SetPassword(ctx context.Context, id int64, passwordHash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	stored.passwordHash = passwordHash
+	stored.TokenVersion++
+	return stored.TokenVersion, nil
+}
+
+func (s *MemoryUserStore) UpdateProfile(ctx context.Context, u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, other := range s.users {
+		if id != u.ID && other.PhoneNumber == u.PhoneNumber {
+			return ErrPhoneNumberTaken
+		}
+	}
+	if stored, ok := s.users[u.ID]; ok {
+		stored.FirstName, stored.LastName = u.FirstName, u.LastName
+		stored.PhoneNumber, stored.Address = u.PhoneNumber, u.Address
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) VerifyEmail(ctx context.Context, id int64, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.Email != email {
+		return sql.ErrNoRows
+	}
+	if stored.VerifiedAt == nil {
+		now := time.Now().UTC()
+		stored.VerifiedAt = &now
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) IsEmailVerified(ctx context.Context, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return stored.VerifiedAt != nil, nil
+}
+
+func (s *MemoryUserStore) TokenVersion(ctx context.Context, id int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return stored.TokenVersion, nil
+}
+
+// bumpTokenVersion revokes every JWT issued to the user so far
+func (s *MemoryUserStore) bumpTokenVersion(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.users[id]; ok {
+		stored.TokenVersion++
+	}
+}
+
+func (s *MemoryUserStore) List(ctx context.Context, f UserFilter) ([]User, string, error) {
+	f = normalizeUserFilter(f)
+	var after int64
+	if f.Cursor != "" {
+		cursor, err := decodeUserCursor(f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = cursor.ID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	search := strings.ToLower(f.Search)
+	users := []User{}
+	for _, stored := range s.users {
+		fields := strings.ToLower(stored.Email + "\x00" + stored.FirstName + "\x00" + stored.LastName + "\x00" + stored.PhoneNumber)
+		switch {
+		case stored.ID <= after:
+		case search != "" && !strings.Contains(fields, search):
+		case f.Role != "" && !HasRole(stored.Roles, f.Role):
+		case f.Suspended != nil && *f.Suspended != (stored.SuspendedAt != nil):
+		default:
+			users = append(users, *stored.copy())
+		}
+	}
+	slices.SortFunc(users, func(a, b User) int { return cmp.Compare(a.ID, b.ID) })
+	users, nextCursor := userPage(users, f.Limit)
+	return users, nextCursor, nil
+}
+
+func (s *MemoryUserStore) Roles(ctx context.Context, id int64) ([]Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return []Role{}, nil
+	}
+	return slices.Clone(stored.Roles), nil
+}
+
+func (s *MemoryUserStore) GrantRole(ctx context.Context, id int64, role Role, grantedBy int64) error {
+	if !role.IsValid() {
+		return ErrUnknownRole
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if ok && !HasRole(stored.Roles, role) {
+		stored.Roles = append(stored.Roles, role)
+		slices.Sort(stored.Roles)
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) RevokeRole(ctx context.Context, id int64, role Role) error {
+	if !role.IsValid() {
+		return ErrUnknownRole
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role == RoleAdmin {
+		admins := 0
+		for otherID, other := range s.users {
+			if otherID != id && HasRole(other.Roles, RoleAdmin) {
+				admins++
+			}
+		}
+		if admins == 0 {
+			return ErrLastAdmin
+		}
+	}
+	stored, ok := s.users[id]
+	if !ok || !HasRole(stored.Roles, role) {
+		return ErrRoleNotGranted
+	}
+	stored.Roles = slices.DeleteFunc(stored.Roles, func(r Role) bool { return r == role })
+	return nil
+}
+
+func (s *MemoryUserStore) IsSuspended(ctx context.Context, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return stored.SuspendedAt != nil, nil
+}
+
+// Suspend revokes the JWTs of the user by bumping their token version; the store has no sessions to revoke
+func (s *MemoryUserStore) Suspend(ctx context.Context, id int64, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.SuspendedAt != nil {
+		return ErrAlreadySuspended
+	}
+	now := time.Now().UTC()
+	stored.SuspendedAt, stored.SuspensionReason = &now, reason
+	stored.TokenVersion++
+	return nil
+}
+
+func (s *MemoryUserStore) Reactivate(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.SuspendedAt == nil {
+		return ErrNotSuspended
+	}
+	stored.SuspendedAt, stored.SuspensionReason = nil, ""
+	return nil
+}
+
+func (s *MemoryUserStore) ForcePasswordReset(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.users[id]; ok {
+		stored.passwordHash = unusablePassword
+		stored.TokenVersion++
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) ScheduleDeletion(ctx context.Context, id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.DeletionScheduledAt != nil || stored.DeletedAt != nil {
+		return ErrDeletionScheduled
+	}
+	if s.lastAdmin(id) {
+		return ErrLastAdminDeletion
+	}
+	at = at.UTC()
+	stored.DeletionScheduledAt = &at
+	return nil
+}
+
+func (s *MemoryUserStore) CancelDeletion(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.DeletionScheduledAt == nil || stored.DeletedAt != nil {
+		return ErrNoDeletionScheduled
+	}
+	stored.DeletionScheduledAt = nil
+	return nil
+}
+
+func (s *MemoryUserStore) DueForDeletion(ctx context.Context, now time.Time) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []int64
+	for id, stored := range s.users {
+		if stored.DeletionScheduledAt != nil && !stored.DeletionScheduledAt.After(now) && stored.DeletedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *MemoryUserStore) Anonymize(ctx context.Context, id int64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	if s.lastAdmin(id) {
+		return ErrLastAdminDeletion
+	}
+	now = now.UTC()
+	stored.Email, stored.PhoneNumber = anonymizedEmail(id), anonymizedPhoneNumber(id)
+	stored.FirstName, stored.LastName, stored.Address, stored.SuspensionReason = "Deleted", "User", "", ""
+	stored.passwordHash = unusablePassword
+	stored.TokenVersion++
+	stored.Roles = []Role{}
+	stored.VerifiedAt, stored.DeletionScheduledAt, stored.DeletedAt = nil, nil, &now
+	return nil
+}
+
+// lastAdmin is checkNotLastAdmin for the memory store; the caller holds the lock
+func (s *MemoryUserStore) lastAdmin(id int64) bool {
+	if !HasRole(s.users[id].Roles, RoleAdmin) {
+		return false
+	}
+	for otherID, other := range s.users {
+		if otherID != id && HasRole(other.Roles, RoleAdmin) && other.DeletionScheduledAt == nil && other.DeletedAt == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryAnnouncementStore keeps announcements and their status history in the process, so that tests of the
+// handlers need no database and can run in parallel
+type MemoryAnnouncementStore struct {
+	mu            sync.Mutex
+	announcements map[int64]*Announcement
+	changes       []StatusChange
+	lastID        int64
+	lastChangeID  int64
+}
+
+func NewMemoryAnnouncementStore() *MemoryAnnouncementStore {
+	return &MemoryAnnouncementStore{announcements: map[int64]*Announcement{}}
+}
+
+func (s *MemoryAnnouncementStore) Create(ctx context.Context, a *Announcement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	a.ID = s.lastID
+	a.CreateDate = time.Now().UTC()
+	a.StartDate = a.StartDate.UTC()
+	a.EndDate = a.EndDate.UTC()
+	a.Status = Pending
+	a.DeletedAt = nil
+
+	stored := *a
+	s.announcements[a.ID] = &stored
+	return nil
+}
+
+func (s *MemoryAnnouncementStore) GetByID(ctx context.Context, id int64) (*Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.announcements[id]
+	if !ok || stored.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	announcement := *stored
+	return &announcement, nil
+}
+
+func (s *MemoryAnnouncementStore) List(ctx context.Context, f AnnouncementFilter) ([]Announcement, string, error) {
+	if err := f.normalize(); err != nil {
+		return nil, "", err
+	}
+	var cursor announcementCursor
+	if f.Cursor != "" {
+		var err error
+		if cursor, err = f.decodeCursor(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// compare orders the positions of two announcements as requested, using the ID to break ties
+	compare := func(a, b announcementCursor) int {
+		order := 0
+		if f.Sort != "id" {
+			order = a.Time.Compare(b.Time)
+		}
+		if order == 0 {
+			order = cmp.Compare(a.ID, b.ID)
+		}
+		if f.Order == "desc" {
+			order = -order
+		}
+		return order
+	}
+	position := func(a Announcement) announcementCursor {
+		c := announcementCursor{Sort: f.Sort, ID: a.ID}
+		c.Time = c.sortValue(a)
+		return c
+	}
+
+	s.mu.Lock()
+	announcements := []Announcement{}
+	for _, a := range s.announcements {
+		if s.matches(a, f) && (f.Cursor == "" || compare(position(*a), cursor) > 0) {
+			announcements = append(announcements, *a)
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(announcements, func(a, b Announcement) int {
+		return compare(position(a), position(b))
+	})
+	if len(announcements) > f.Limit+1 {
+		announcements = announcements[:f.Limit+1]
+	}
+	announcements, nextCursor := f.page(announcements)
+	return announcements, nextCursor, nil
+}
+
+// matches reports whether a live announcement passes every constraint of the filter, apart from the cursor
+func (s *MemoryAnnouncementStore) matches(a *Announcement, f AnnouncementFilter) bool {
+	if a.DeletedAt != nil {
+		return false
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, a.Status) {
+		return false
+	}
+	if f.OwnerID != nil && a.OwnerID != *f.OwnerID {
+		return false
+	}
+	if f.PublicOnly && a.Status != Active && (f.VisibleTo == 0 || a.OwnerID != f.VisibleTo) {
+		return false
+	}
+	for _, r := range f.timeRanges() {
+		value := announcementCursor{Sort: r.column}.sortValue(*a)
+		if (r.from != nil && value.Before(*r.from)) || (r.to != nil && value.After(*r.to)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *MemoryAnnouncementStore) ListDeleted(ctx context.Context) ([]Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	announcements := []Announcement{}
+	for _, a := range s.announcements {
+		if a.DeletedAt != nil {
+			announcements = append(announcements, *a)
+		}
+	}
+	slices.SortFunc(announcements, func(a, b Announcement) int {
+		return b.DeletedAt.Compare(*a.DeletedAt)
+	})
+	return announcements, nil
+}
+
+func (s *MemoryAnnouncementStore) ListByOwner(ctx context.Context, ownerID int64) ([]Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	announcements := []Announcement{}
+	for _, a := range s.announcements {
+		if a.OwnerID == ownerID {
+			announcements = append(announcements, *a)
+		}
+	}
+	slices.SortFunc(announcements, func(a, b Announcement) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return announcements, nil
+}
+
+func (s *MemoryAnnouncementStore) Update(ctx context.Context, a *Announcement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.announcements[a.ID]
+	if !ok || stored.DeletedAt != nil || !stored.IsEditable() {
+		return ErrAnnouncementLocked
+	}
+	a.StartDate = a.StartDate.UTC()
+	a.EndDate = a.EndDate.UTC()
+	stored.Text, stored.StartDate, stored.EndDate = a.Text, a.StartDate, a.EndDate
+	return nil
+}
+
+func (s *MemoryAnnouncementStore) ChangeStatus(ctx context.Context, change *StatusChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.announcements[change.AnnouncementID]
+	if !ok || stored.DeletedAt != nil || stored.Status != change.FromStatus {
+		return ErrStatusChanged
+	}
+	stored.Status = change.ToStatus
+	s.lastChangeID++
+	change.ID = s.lastChangeID
+	s.changes = append(s.changes, *change)
+	return nil
+}
+
+func (s *MemoryAnnouncementStore) StatusChanges(ctx context.Context, announcementID int64) ([]StatusChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := []StatusChange{}
+	for _, c := range s.changes {
+		if c.AnnouncementID == announcementID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (s *MemoryAnnouncementStore) StatusChangesByOwner(ctx context.Context, ownerID int64) ([]StatusChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := []StatusChange{}
+	for _, c := range s.changes {
+		if a, ok := s.announcements[c.AnnouncementID]; ok && a.OwnerID == ownerID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (s *MemoryAnnouncementStore) Delete(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.announcements[id]
+	if !ok || stored.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := time.Now().UTC()
+	stored.DeletedAt = &now
+	return nil
+}
+
+func (s *MemoryAnnouncementStore) Restore(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.announcements[id]
+	if !ok || stored.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	stored.DeletedAt = nil
+	return nil
+}
+
+func (s *MemoryAnnouncementStore) PurgeDeleted(ctx context.Context, cutoff time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for id, a := range s.announcements {
+		if a.DeletedAt != nil && a.DeletedAt.Before(cutoff) {
+			delete(s.announcements, id)
+			purged++
+		}
+	}
+	s.changes = slices.DeleteFunc(s.changes, func(c StatusChange) bool {
+		_, ok := s.announcements[c.AnnouncementID]
+		return !ok
+	})
+	return purged, nil
+}
+
+// ownerOf returns the owner of the announcement, deleted or not
+func (s *MemoryAnnouncementStore) ownerOf(id int64) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.announcements[id]
+	if !ok {
+		return 0, false
+	}
+	return a.OwnerID, true
+}
+
+// MemorySessionStore keeps sessions and refresh tokens in the process, for the users of a MemoryUserStore whose
+// token versions it checks and bumps
+type MemorySessionStore struct {
+	mu            sync.Mutex
+	users         *MemoryUserStore
+	sessions      map[int64]*memorySession
+	refreshTokens map[string]*memoryRefreshToken
+	revokedTokens map[string]time.Time
+	lastID        int64
+}
+
+type memorySession struct {
+	Session
+	userID  int64
+	revoked bool
+}
+
+// memoryRefreshToken is kept under the hash of the token, like in the refresh_tokens table
+type memoryRefreshToken struct {
+	userID, sessionID int64
+	familyID          string
+	tokenVersion      int64
+	expiresAt         time.Time
+	used, revoked     bool
+}
+
+func NewMemorySessionStore(users *MemoryUserStore) *MemorySessionStore {
+	return &MemorySessionStore{
+		users:         users,
+		sessions:      map[int64]*memorySession{},
+		refreshTokens: map[string]*memoryRefreshToken{},
+		revokedTokens: map[string]time.Time{},
+	}
+}
+
+func (s *MemorySessionStore) Start(ctx context.Context, userId, tokenVersion int64, ipAddress, userAgent string, ttl time.Duration) (int64, string, error) {
+	familyId, err := helpers.GenerateRandomToken()
+	if err != nil {
+		return 0, "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	s.lastID++
+	s.sessions[s.lastID] = &memorySession{
+		Session: Session{ID: s.lastID, IPAddress: ipAddress, UserAgent: truncateUserAgent(userAgent), CreatedAt: now, LastSeenAt: now},
+		userID:  userId,
+	}
+	refreshToken, err := s.issue(userId, s.lastID, familyId, tokenVersion, ttl)
+	if err != nil {
+		return 0, "", err
+	}
+	return s.lastID, refreshToken, nil
+}
+
+// issue is insertRefreshToken for the memory store; the caller holds the lock
+func (s *MemorySessionStore) issue(userId, sessionId int64, familyId string, tokenVersion int64, ttl time.Duration) (string, error) {
+	token, err := helpers.GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	s.refreshTokens[helpers.HashToken(token)] = &memoryRefreshToken{
+		userID:       userId,
+		sessionID:    sessionId,
+		familyID:     familyId,
+		tokenVersion: tokenVersion,
+		expiresAt:    time.Now().UTC().Add(ttl),
+	}
+	return token, nil
+}
+
+func (s *MemorySessionStore) Touch(ctx context.Context, sessionId, userId int64, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionId]
+	if !ok || session.userID != userId {
+		return false, nil
+	}
+	if !session.revoked && now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		session.LastSeenAt = now.UTC()
+	}
+	return !session.revoked, nil
+}
+
+func (s *MemorySessionStore) List(ctx context.Context, userId int64) ([]Session, error) {
+	tokenVersion, err := s.users.TokenVersion(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []Session{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	refreshable := map[int64]bool{}
+	for _, t := range s.refreshTokens {
+		if t.userID == userId && !t.used && !t.revoked && t.expiresAt.After(now) && t.tokenVersion == tokenVersion {
+			refreshable[t.sessionID] = true
+		}
+	}
+	sessions := []Session{}
+	for id, session := range s.sessions {
+		if session.userID == userId && !session.revoked && refreshable[id] {
+			sessions = append(sessions, session.Session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		if order := b.LastSeenAt.Compare(a.LastSeenAt); order != 0 {
+			return order
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return sessions, nil
+}
+
+func (s *MemorySessionStore) Revoke(ctx context.Context, userId, sessionId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionId]
+	if !ok || session.userID != userId || session.revoked {
+		return sql.ErrNoRows
+	}
+	session.revoked = true
+	for _, t := range s.refreshTokens {
+		if t.sessionID == sessionId {
+			t.revoked = true
+		}
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) RotateRefreshToken(ctx context.Context, token string, ttl time.Duration) (*RefreshGrant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claimed, ok := s.refreshTokens[helpers.HashToken(token)]
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	if claimed.used {
+		s.revokeFamily(claimed.familyID)
+		return nil, ErrRefreshTokenReused
+	}
+	tokenVersion, err := s.users.TokenVersion(ctx, claimed.userID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := time.Now().UTC()
+	if claimed.revoked || !claimed.expiresAt.After(now) || claimed.tokenVersion != tokenVersion {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	claimed.used = true
+	if session, ok := s.sessions[claimed.sessionID]; ok {
+		session.LastSeenAt = now
+	}
+	next, err := s.issue(claimed.userID, claimed.sessionID, claimed.familyID, tokenVersion, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshGrant{UserID: claimed.userID, SessionID: claimed.sessionID, RefreshToken: next}, nil
+}
+
+// revokeFamily revokes every refresh token descended from the same login; the caller holds the lock
+func (s *MemorySessionStore) revokeFamily(familyId string) {
+	for _, t := range s.refreshTokens {
+		if t.familyID == familyId {
+			t.revoked = true
+		}
+	}
+}
+
+func (s *MemorySessionStore) RevokeRefreshToken(ctx context.Context, userId int64, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.refreshTokens[helpers.HashToken(token)]; ok && t.userID == userId {
+		s.revokeFamily(t.familyID)
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) RevokeAll(ctx context.Context, userId int64) error {
+	s.users.bumpTokenVersion(userId)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.refreshTokens {
+		if t.userID == userId {
+			t.revoked = true
+		}
+	}
+	for _, session := range s.sessions {
+		if session.userID == userId {
+			session.revoked = true
+		}
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) RevokeAccessToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.revokedTokens[tokenId]; !ok {
+		s.revokedTokens[tokenId] = expiresAt.UTC()
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) IsAccessTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, revoked := s.revokedTokens[tokenId]
+	return revoked, nil
+}
+
+func (s *MemorySessionStore) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for hash, t := range s.refreshTokens {
+		if t.expiresAt.Before(now) {
+			delete(s.refreshTokens, hash)
+			purged++
+		}
+	}
+	for tokenId, expiresAt := range s.revokedTokens {
+		if expiresAt.Before(now) {
+			delete(s.revokedTokens, tokenId)
+			purged++
+		}
+	}
+	inUse := map[int64]bool{}
+	for _, t := range s.refreshTokens {
+		inUse[t.sessionID] = true
+	}
+	for id, session := range s.sessions {
+		if session.CreatedAt.Before(now) && !inUse[id] {
+			delete(s.sessions, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// MemoryAPIKeyStore keeps API keys in the process
+type MemoryAPIKeyStore struct {
+	mu     sync.Mutex
+	keys   map[int64]*memoryAPIKey
+	lastID int64
+}
+
+type memoryAPIKey struct {
+	APIKey
+	keyHash string
+	revoked bool
+}
+
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: map[int64]*memoryAPIKey{}}
+}
+
+// copy returns the key without anything a caller could use to change the stored one
+func (k *memoryAPIKey) copy() *APIKey {
+	key := k.APIKey
+	key.Scopes = slices.Clone(k.Scopes)
+	return &key
+}
+
+func (s *MemoryAPIKeyStore) Create(ctx context.Context, key *APIKey, keyHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	key.ID = s.lastID
+	stored := &memoryAPIKey{APIKey: *key, keyHash: keyHash}
+	stored.Scopes = slices.Clone(key.Scopes)
+	s.keys[key.ID] = stored
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) List(ctx context.Context, userId int64) ([]APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []APIKey{}
+	for _, stored := range s.keys {
+		if stored.UserID == userId && !stored.revoked {
+			keys = append(keys, *stored.copy())
+		}
+	}
+	slices.SortFunc(keys, func(a, b APIKey) int { return cmp.Compare(b.ID, a.ID) })
+	return keys, nil
+}
+
+func (s *MemoryAPIKeyStore) Revoke(ctx context.Context, userId, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.keys[id]
+	if !ok || stored.UserID != userId || stored.revoked {
+		return sql.ErrNoRows
+	}
+	stored.revoked = true
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) GetByPrefix(ctx context.Context, prefix string) (*APIKey, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.keys {
+		if stored.Prefix == prefix && !stored.revoked {
+			return stored.copy(), stored.keyHash, nil
+		}
+	}
+	return nil, "", sql.ErrNoRows
+}
+
+func (s *MemoryAPIKeyStore) MarkUsed(ctx context.Context, id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.keys[id]; ok {
+		at = at.UTC()
+		stored.LastUsedAt = &at
+	}
+	return nil
+}
+
+// MemoryTwoFactorStore keeps TOTP secrets and recovery codes in the process
+type MemoryTwoFactorStore struct {
+	mu          sync.Mutex
+	credentials map[int64]TOTPCredential
+	// recoveryCodes maps the hashes of the recovery codes of each user to whether they were used
+	recoveryCodes map[int64]map[string]bool
+}
+
+func NewMemoryTwoFactorStore() *MemoryTwoFactorStore {
+	return &MemoryTwoFactorStore{credentials: map[int64]TOTPCredential{}, recoveryCodes: map[int64]map[string]bool{}}
+}
+
+func (s *MemoryTwoFactorStore) Enabled(ctx context.Context, userId int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.credentials[userId].Confirmed, nil
+}
+
+func (s *MemoryTwoFactorStore) Credential(ctx context.Context, userId int64) (*TOTPCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential, ok := s.credentials[userId]
+	if !ok {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return &credential, nil
+}
+
+func (s *MemoryTwoFactorStore) SaveSecret(ctx context.Context, userId int64, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential := s.credentials[userId]
+	credential.Secret, credential.LastUsedStep = secret, 0
+	s.credentials[userId] = credential
+	return nil
+}
+
+func (s *MemoryTwoFactorStore) Confirm(ctx context.Context, userId, step int64, recoveryCodeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential, ok := s.credentials[userId]
+	if !ok || credential.Confirmed {
+		return ErrTwoFactorEnabled
+	}
+	credential.Confirmed, credential.LastUsedStep = true, step
+	s.credentials[userId] = credential
+
+	codes := map[string]bool{}
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = false
+	}
+	s.recoveryCodes[userId] = codes
+	return nil
+}
+
+func (s *MemoryTwoFactorStore) UseStep(ctx context.Context, userId, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential, ok := s.credentials[userId]
+	if !ok || credential.LastUsedStep >= step {
+		return ErrInvalidTwoFactorCode
+	}
+	credential.LastUsedStep = step
+	s.credentials[userId] = credential
+	return nil
+}
+
+func (s *MemoryTwoFactorStore) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, ok := s.recoveryCodes[userId][codeHash]
+	if !ok || used {
+		return ErrInvalidTwoFactorCode
+	}
+	s.recoveryCodes[userId][codeHash] = true
+	return nil
+}
+
+func (s *MemoryTwoFactorStore) Disable(ctx context.Context, userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.credentials, userId)
+	delete(s.recoveryCodes, userId)
+	return nil
+}
+
+// MemoryBlacklistStore keeps the history of the blacklist in the process
+type MemoryBlacklistStore struct {
+	mu      sync.Mutex
+	changes []BlacklistChange
+	lastID  int64
+}
+
+func NewMemoryBlacklistStore() *MemoryBlacklistStore {
+	return &MemoryBlacklistStore{}
+}
+
+func (s *MemoryBlacklistStore) Latest(ctx context.Context, userId int64) (*BlacklistChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		if s.changes[i].UserID == userId {
+			change := s.changes[i]
+			return &change, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *MemoryBlacklistStore) Record(ctx context.Context, change *BlacklistChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	change.ID = s.lastID
+	s.changes = append(s.changes, *change)
+	return nil
+}
+
+func (s *MemoryBlacklistStore) Changes(ctx context.Context, userId int64) ([]BlacklistChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := []BlacklistChange{}
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		if userId == 0 || s.changes[i].UserID == userId {
+			changes = append(changes, s.changes[i])
+		}
+	}
+	return changes, nil
+}
+
+// MemoryPasswordResetStore keeps reset tokens in the process, for the users of a MemoryUserStore whose
+// passwords it sets
+type MemoryPasswordResetStore struct {
+	mu     sync.Mutex
+	users  *MemoryUserStore
+	resets map[int64]*memoryPasswordReset
+	lastID int64
+}
+
+type memoryPasswordReset struct {
+	userID    int64
+	tokenHash string
+	expiresAt time.Time
+	used      bool
+}
+
+func NewMemoryPasswordResetStore(users *MemoryUserStore) *MemoryPasswordResetStore {
+	return &MemoryPasswordResetStore{users: users, resets: map[int64]*memoryPasswordReset{}}
+}
+
+func (s *MemoryPasswordResetStore) Create(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	s.resets[s.lastID] = &memoryPasswordReset{userID: userId, tokenHash: tokenHash, expiresAt: expiresAt.UTC()}
+	return nil
+}
+
+func (s *MemoryPasswordResetStore) Find(ctx context.Context, tokenHash string, now time.Time) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, reset := range s.resets {
+		if reset.tokenHash == tokenHash && !reset.used && reset.expiresAt.After(now) {
+			return id, reset.userID, nil
+		}
+	}
+	return 0, 0, ErrInvalidResetToken
+}
+
+func (s *MemoryPasswordResetStore) Consume(ctx context.Context, resetId, userId int64, passwordHash string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reset, ok := s.resets[resetId]
+	if !ok || reset.used || !reset.expiresAt.After(now) {
+		return ErrInvalidResetToken
+	}
+	for _, other := range s.resets {
+		if other.userID == userId {
+			other.used = true
+		}
+	}
+	_, err := s.users.SetPassword(ctx, userId, passwordHash)
+	return err
+}
+
+// MemoryFlagStore keeps flags in the process, on the announcements of a MemoryAnnouncementStore
+type MemoryFlagStore struct {
+	mu            sync.Mutex
+	announcements *MemoryAnnouncementStore
+	flags         map[int64]*Flag
+	lastID        int64
+}
+
+func NewMemoryFlagStore(announcements *MemoryAnnouncementStore) *MemoryFlagStore {
+	return &MemoryFlagStore{announcements: announcements, flags: map[int64]*Flag{}}
+}
+
+func (s *MemoryFlagStore) Create(ctx context.Context, f *Flag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.flags {
+		if other.AnnouncementID == f.AnnouncementID && other.UserID == f.UserID {
+			return ErrAlreadyFlagged
+		}
+	}
+	s.lastID++
+	f.ID = s.lastID
+	stored := *f
+	s.flags[f.ID] = &stored
+	return nil
+}
+
+func (s *MemoryFlagStore) Resolve(ctx context.Context, id int64, status FlagStatus, resolvedBy int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.flags[id]
+	if !ok || stored.Status != FlagOpen {
+		return ErrFlagNotOpen
+	}
+	at = at.UTC()
+	stored.Status, stored.ResolvedBy, stored.ResolvedAt = status, &resolvedBy, &at
+	return nil
+}
+
+func (s *MemoryFlagStore) GetByID(ctx context.Context, id int64) (*Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.flags[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	flag := *stored
+	return &flag, nil
+}
+
+func (s *MemoryFlagStore) List(ctx context.Context, status FlagStatus, announcementID int64) ([]Flag, error) {
+	return s.filter(func(f *Flag) bool {
+		return (status == "" || f.Status == status) && (announcementID == 0 || f.AnnouncementID == announcementID)
+	}), nil
+}
+
+func (s *MemoryFlagStore) ListByUser(ctx context.Context, userID int64) ([]Flag, error) {
+	return s.filter(func(f *Flag) bool { return f.UserID == userID }), nil
+}
+
+func (s *MemoryFlagStore) ListAgainstUser(ctx context.Context, userID int64) ([]Flag, error) {
+	return s.filter(func(f *Flag) bool {
+		owner, ok := s.announcements.ownerOf(f.AnnouncementID)
+		return ok && owner == userID
+	}), nil
+}
+
+// filter returns the flags that match, newest first
+func (s *MemoryFlagStore) filter(match func(f *Flag) bool) []Flag {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags := []Flag{}
+	for _, f := range s.flags {
+		if match(f) {
+			flags = append(flags, *f)
+		}
+	}
+	slices.SortFunc(flags, func(a, b Flag) int {
+		if order := b.CreatedOn.Compare(a.CreatedOn); order != 0 {
+			return order
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return flags
+}
+
+func (s *MemoryFlagStore) CountOpen(ctx context.Context, announcementID int64) (int, error) {
+	return len(s.filter(func(f *Flag) bool { return f.AnnouncementID == announcementID && f.Status == FlagOpen })), nil
+}
+
+func (s *MemoryFlagStore) Counts(ctx context.Context) ([]FlagCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byAnnouncement := map[int64]*FlagCount{}
+	for _, f := range s.flags {
+		count, ok := byAnnouncement[f.AnnouncementID]
+		if !ok {
+			count = &FlagCount{AnnouncementID: f.AnnouncementID}
+			byAnnouncement[f.AnnouncementID] = count
+		}
+		count.Total++
+		if f.Status == FlagOpen {
+			count.Open++
+		}
+	}
+	counts := []FlagCount{}
+	for _, count := range byAnnouncement {
+		counts = append(counts, *count)
+	}
+	slices.SortFunc(counts, func(a, b FlagCount) int {
+		return cmp.Or(cmp.Compare(b.Open, a.Open), cmp.Compare(b.Total, a.Total), cmp.Compare(a.AnnouncementID, b.AnnouncementID))
+	})
+	return counts, nil
+}
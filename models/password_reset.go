@@ -0,0 +1,114 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/helpers"
+)
+
+// ErrInvalidResetToken is returned when a reset token is unknown, expired or already used
+var ErrInvalidResetToken = BadRequest("invalid_reset_token", "Invalid or expired reset token")
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// PasswordResetStore keeps the reset tokens of users; only their hashes are stored
+type PasswordResetStore interface {
+	// Create stores a reset token for the user, valid until expiresAt
+	Create(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error
+	// Find returns the ID of the reset with the token and the user it is for. It fails with ErrInvalidResetToken
+	// when the token is unknown, used or expired at now.
+	Find(ctx context.Context, tokenHash string, now time.Time) (int64, int64, error)
+	// Consume uses the reset and every other outstanding reset of the user, stores the new password hash and bumps
+	// the token version. It fails with ErrInvalidResetToken when the reset was used or expired in the meantime.
+	Consume(ctx context.Context, resetId, userId int64, passwordHash string, now time.Time) error
+}
+
+// SQLPasswordResetStore keeps reset tokens in the password_resets table of DB
+type SQLPasswordResetStore struct {
+	DB *sql.DB
+}
+
+// CreatePasswordReset issues a single-use reset token for the user
+func CreatePasswordReset(ctx context.Context, resets PasswordResetStore, userId int64, ttl time.Duration) (string, error) {
+	token, err := helpers.GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := resets.Create(ctx, userId, helpers.HashToken(token), time.Now().UTC().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and revokes every JWT issued to the user
+func ResetPassword(ctx context.Context, resets PasswordResetStore, token, newPassword string) error {
+	// The token is checked before the password is hashed, since anyone can call this without credentials
+	resetId, userId, err := resets.Find(ctx, helpers.HashToken(token), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := helpers.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return resets.Consume(ctx, resetId, userId, hashedPassword, time.Now().UTC())
+}
+
+func (s SQLPasswordResetStore) Create(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error {
+	query := "INSERT INTO password_resets (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)"
+	_, err := s.DB.ExecContext(ctx, query, userId, tokenHash, expiresAt.UTC(), time.Now().UTC())
+	return err
+}
+
+func (s SQLPasswordResetStore) Find(ctx context.Context, tokenHash string, now time.Time) (int64, int64, error) {
+	var resetId, userId int64
+	query := "SELECT id, user_id FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?"
+	err := s.DB.QueryRowContext(ctx, query, tokenHash, now.UTC()).Scan(&resetId, &userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, ErrInvalidResetToken
+	}
+	return resetId, userId, err
+}
+
+func (s SQLPasswordResetStore) Consume(ctx context.Context, resetId, userId int64, passwordHash string, now time.Time) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Another request may have used the token while the password was hashed
+	now = now.UTC()
+	query := "UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL AND expires_at > ?"
+	err = affectedOne(tx.ExecContext(ctx, query, now, resetId, now))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// Consume any other outstanding token too, so older reset emails stop working
+	_, err = tx.ExecContext(ctx, "UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL", now, userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET password = ?, token_version = token_version + 1 WHERE id = ?", passwordHash, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
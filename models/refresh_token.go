@@ -0,0 +1,187 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/helpers"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked
+	ErrInvalidRefreshToken = Unauthorized("invalid_refresh_token", "Invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = Unauthorized("refresh_token_reused", "Refresh token reuse detected, please log in again")
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertRefreshToken issues a refresh token of the session and family; only the hash of the token is stored
+func insertRefreshToken(ctx context.Context, conn execer, userId, sessionId int64, familyId string, tokenVersion int64, ttl time.Duration) (string, error) {
+	token, err := helpers.GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	query := `
+	INSERT INTO refresh_tokens (user_id, session_id, family_id, token_hash, token_version, expires_at, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = conn.ExecContext(ctx, query, userId, sessionId, familyId, helpers.HashToken(token), tokenVersion, now.Add(ttl), now)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RefreshGrant is what a rotated refresh token entitles to: a new access token for the user and session,
+// and the successor refresh token
+type RefreshGrant struct {
+	UserID       int64
+	SessionID    int64
+	RefreshToken string
+}
+
+func (s SQLSessionStore) RotateRefreshToken(ctx context.Context, token string, ttl time.Duration) (*RefreshGrant, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Claiming the token is the first statement so that the transaction takes the write lock before reading:
+	// of two concurrent refreshes with the same token, the second waits and then sees it as reused
+	now := time.Now().UTC()
+	tokenHash := helpers.HashToken(token)
+	claimed, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL", now, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := claimed.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		userId, sessionId            int64
+		tokenVersion, currentVersion int64
+		familyId                     string
+		expiresAt                    time.Time
+		revokedAt                    sql.NullTime
+	)
+	query := `
+	SELECT r.user_id, r.session_id, r.family_id, r.token_version, r.expires_at, r.revoked_at, u.token_version
+	FROM refresh_tokens r JOIN users u ON u.id = r.user_id
+	WHERE r.token_hash = ?`
+	err = tx.QueryRowContext(ctx, query, tokenHash).Scan(&userId, &sessionId, &familyId, &tokenVersion, &expiresAt, &revokedAt, &currentVersion)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if affected == 0 {
+		_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL", now, familyId)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	// Changing the password or logging out everywhere bumps the version, which invalidates refresh tokens too.
+	// Returning rolls back the claim, as the token was never valid.
+	if revokedAt.Valid || !expiresAt.After(now) || tokenVersion != currentVersion {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Refreshing is activity on the session too
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET last_seen_at = ? WHERE id = ?", now, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	next, err := insertRefreshToken(ctx, tx, userId, sessionId, familyId, currentVersion, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshGrant{UserID: userId, SessionID: sessionId, RefreshToken: next}, tx.Commit()
+}
+
+func (s SQLSessionStore) RevokeRefreshToken(ctx context.Context, userId int64, token string) error {
+	query := `
+	UPDATE refresh_tokens SET revoked_at = ?
+	WHERE revoked_at IS NULL AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = ? AND user_id = ?)`
+	_, err := s.DB.ExecContext(ctx, query, time.Now().UTC(), helpers.HashToken(token), userId)
+	return err
+}
+
+func (s SQLSessionStore) RevokeAll(ctx context.Context, userId int64) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeAllTokens(ctx, tx, userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// revokeAllTokens bumps the token version and revokes the refresh tokens and sessions of the user, within a transaction
+func revokeAllTokens(ctx context.Context, tx execer, userId int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE users SET token_version = token_version + 1 WHERE id = ?", userId)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", now, userId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", now, userId)
+	return err
+}
+
+func (s SQLSessionStore) RevokeAccessToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	query := "INSERT OR IGNORE INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)"
+	_, err := s.DB.ExecContext(ctx, query, tokenId, expiresAt.UTC())
+	return err
+}
+
+func (s SQLSessionStore) IsAccessTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	var revoked bool
+	query := "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE token_id = ?)"
+	err := s.DB.QueryRowContext(ctx, query, tokenId).Scan(&revoked)
+	return revoked, err
+}
+
+func (s SQLSessionStore) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	var purged int64
+	for _, query := range []string{
+		"DELETE FROM refresh_tokens WHERE expires_at < ?",
+		"DELETE FROM revoked_tokens WHERE expires_at < ?",
+		"DELETE FROM sessions WHERE created_at < ? AND NOT EXISTS (SELECT 1 FROM refresh_tokens WHERE session_id = sessions.id)",
+	} {
+		result, err := s.DB.ExecContext(ctx, query, now.UTC())
+		if err != nil {
+			return purged, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += affected
+	}
+	return purged, nil
+}
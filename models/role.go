@@ -0,0 +1,180 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrUnknownRole is returned for role names outside the fixed set
+	ErrUnknownRole = errors.New("unknown role")
+	// ErrLastAdmin is returned when revoking the admin role would leave no admin
+	ErrLastAdmin = Conflict("last_admin", "Cannot revoke the admin role from the last admin")
+	// ErrRoleNotGranted is returned when revoking a role the user does not have
+	ErrRoleNotGranted = NotFound("role_not_granted", "User does not have this role")
+)
+
+type Role string
+
+const (
+	RoleAdvertiser Role = "advertiser"
+	RoleModerator  Role = "moderator"
+	RoleAdmin      Role = "admin"
+	RoleAuditor    Role = "auditor"
+)
+
+type Permission string
+
+const (
+	PermReadAnnouncements     Permission = "announcements:read"
+	PermCreateAnnouncements   Permission = "announcements:create"
+	PermReadAllAnnouncements  Permission = "announcements:read_all"
+	PermModerateAnnouncements Permission = "announcements:moderate"
+	PermDeleteAnnouncements   Permission = "announcements:delete"
+	PermCreateFlags           Permission = "flags:create"
+	PermReadFlags             Permission = "flags:read"
+	PermResolveFlags          Permission = "flags:resolve"
+	PermReadBlacklist         Permission = "blacklist:read"
+	PermManageBlacklist       Permission = "blacklist:manage"
+	PermManageRoles           Permission = "roles:manage"
+	PermUnlockUsers           Permission = "users:unlock"
+	PermReadUsers             Permission = "users:read"
+	PermManageUsers           Permission = "users:manage"
+)
+
+// rolePermissions is the permission table: what each role is allowed to do
+var rolePermissions = map[Role][]Permission{
+	RoleAdvertiser: {PermReadAnnouncements, PermCreateAnnouncements, PermCreateFlags},
+	RoleModerator: {
+		PermReadAnnouncements, PermReadAllAnnouncements, PermModerateAnnouncements,
+		PermCreateFlags, PermReadFlags, PermResolveFlags,
+		PermReadBlacklist,
+	},
+	RoleAuditor: {
+		PermReadAnnouncements, PermReadAllAnnouncements,
+		PermReadFlags,
+		PermReadBlacklist,
+	},
+	RoleAdmin: {
+		PermReadAnnouncements, PermCreateAnnouncements, PermReadAllAnnouncements, PermModerateAnnouncements, PermDeleteAnnouncements,
+		PermCreateFlags, PermReadFlags, PermResolveFlags,
+		PermReadBlacklist, PermManageBlacklist,
+		PermManageRoles, PermUnlockUsers, PermReadUsers, PermManageUsers,
+	},
+}
+
+// Roles lists every role, for validation and documentation
+var Roles = []Role{RoleAdvertiser, RoleModerator, RoleAdmin, RoleAuditor}
+
+// Permissions lists every permission, which are also the scopes an API key can be limited to
+var Permissions = []Permission{
+	PermReadAnnouncements, PermCreateAnnouncements, PermReadAllAnnouncements, PermModerateAnnouncements, PermDeleteAnnouncements,
+	PermCreateFlags, PermReadFlags, PermResolveFlags,
+	PermReadBlacklist, PermManageBlacklist,
+	PermManageRoles, PermUnlockUsers, PermReadUsers, PermManageUsers,
+}
+
+func (p Permission) IsValid() bool {
+	for _, permission := range Permissions {
+		if permission == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// HasPermission reports whether any of the roles grants the permission
+func HasPermission(roles []Role, permission Permission) bool {
+	for _, role := range roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasRole reports whether role is among roles
+func HasRole(roles []Role, role Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type RoleRequest struct {
+	Role Role `json:"role" binding:"required" enums:"advertiser,moderator,admin,auditor"`
+}
+
+func (s SQLUserStore) Roles(ctx context.Context, id int64) ([]Role, error) {
+	return queryRoles(ctx, s.DB, id)
+}
+
+func queryRoles(ctx context.Context, conn *sql.DB, userId int64) ([]Role, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT role FROM user_roles WHERE user_id = ? ORDER BY role", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (s SQLUserStore) GrantRole(ctx context.Context, id int64, role Role, grantedBy int64) error {
+	if !role.IsValid() {
+		return ErrUnknownRole
+	}
+	query := "INSERT OR IGNORE INTO user_roles (user_id, role, granted_by, granted_at) VALUES (?, ?, ?, ?)"
+	_, err := s.DB.ExecContext(ctx, query, id, role, actor(grantedBy), time.Now().UTC())
+	return err
+}
+
+func (s SQLUserStore) RevokeRole(ctx context.Context, id int64, role Role) error {
+	if !role.IsValid() {
+		return ErrUnknownRole
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if role == RoleAdmin {
+		var admins int
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_roles WHERE role = ? AND user_id != ?", RoleAdmin, id).Scan(&admins)
+		if err != nil {
+			return err
+		}
+		if admins == 0 {
+			return ErrLastAdmin
+		}
+	}
+
+	err = affectedOne(tx.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = ? AND role = ?", id, role))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRoleNotGranted
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/helpers"
+)
+
+// sessionTouchInterval is how stale last_seen_at may get, so that not every request writes to the database
+const sessionTouchInterval = time.Minute
+
+// maxUserAgentLength keeps clients from storing arbitrary amounts of data through the header
+const maxUserAgentLength = 512
+
+// Session is a login on one device; it lives as long as its refresh tokens
+type Session struct {
+	ID         int64     `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Current marks the session of the token the list was requested with
+	Current bool `json:"current"`
+}
+
+// SessionStore keeps the logins of users, their refresh tokens and the access tokens revoked by a logout.
+// Every method takes the context of the request it serves, like those of UserStore.
+type SessionStore interface {
+	// Start records a login and issues the first refresh token of the session
+	Start(ctx context.Context, userId, tokenVersion int64, ipAddress, userAgent string, ttl time.Duration) (int64, string, error)
+	// Touch reports whether the session of an access token is still active, and records that it was seen.
+	// The time it was last seen is only written when it is more than sessionTouchInterval old.
+	Touch(ctx context.Context, sessionId, userId int64, now time.Time) (bool, error)
+	// List returns the sessions of the user that can still be refreshed, most recently seen first
+	List(ctx context.Context, userId int64) ([]Session, error)
+	// Revoke signs a device out: its refresh tokens stop working at once, and so do its access tokens since
+	// the session is checked on every request. It fails with sql.ErrNoRows for sessions of other users or already revoked.
+	Revoke(ctx context.Context, userId, sessionId int64) error
+
+	// RotateRefreshToken consumes a refresh token and issues its successor in the same family. Presenting a token
+	// that was already rotated means it leaked, so the whole family is revoked and ErrRefreshTokenReused returned.
+	RotateRefreshToken(ctx context.Context, token string, ttl time.Duration) (*RefreshGrant, error)
+	// RevokeRefreshToken revokes the family of one of the user's refresh tokens; unknown tokens are ignored
+	RevokeRefreshToken(ctx context.Context, userId int64, token string) error
+	// RevokeAll logs the user out everywhere: every access and refresh token issued so far stops working
+	RevokeAll(ctx context.Context, userId int64) error
+	// RevokeAccessToken denylists a single access token until it would have expired anyway
+	RevokeAccessToken(ctx context.Context, tokenId string, expiresAt time.Time) error
+	// IsAccessTokenRevoked reports whether the access token was revoked by a logout
+	IsAccessTokenRevoked(ctx context.Context, tokenId string) (bool, error)
+	// PurgeExpired deletes refresh tokens and denylist entries that can no longer be used, and the sessions
+	// left without refresh tokens, and returns how many it deleted
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// SQLSessionStore keeps sessions in the sessions, refresh_tokens and revoked_tokens tables of DB
+type SQLSessionStore struct {
+	DB *sql.DB
+}
+
+// truncateUserAgent cuts the header down to maxUserAgentLength before it is stored
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+func (s SQLSessionStore) Start(ctx context.Context, userId, tokenVersion int64, ipAddress, userAgent string, ttl time.Duration) (int64, string, error) {
+	familyId, err := helpers.GenerateRandomToken()
+	if err != nil {
+		return 0, "", err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	query := "INSERT INTO sessions (user_id, ip_address, user_agent, created_at, last_seen_at) VALUES (?, ?, ?, ?, ?)"
+	result, err := tx.ExecContext(ctx, query, userId, ipAddress, truncateUserAgent(userAgent), now, now)
+	if err != nil {
+		return 0, "", err
+	}
+	sessionId, err := result.LastInsertId()
+	if err != nil {
+		return 0, "", err
+	}
+
+	refreshToken, err := insertRefreshToken(ctx, tx, userId, sessionId, familyId, tokenVersion, ttl)
+	if err != nil {
+		return 0, "", err
+	}
+	return sessionId, refreshToken, tx.Commit()
+}
+
+func (s SQLSessionStore) Touch(ctx context.Context, sessionId, userId int64, now time.Time) (bool, error) {
+	var active bool
+	var lastSeenAt time.Time
+	query := "SELECT revoked_at IS NULL, last_seen_at FROM sessions WHERE id = ? AND user_id = ?"
+	err := s.DB.QueryRowContext(ctx, query, sessionId, userId).Scan(&active, &lastSeenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Purged sessions count as revoked
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if active && now.Sub(lastSeenAt) > sessionTouchInterval {
+		_, err = s.DB.ExecContext(ctx, "UPDATE sessions SET last_seen_at = ? WHERE id = ?", now.UTC(), sessionId)
+	}
+	return active, err
+}
+
+func (s SQLSessionStore) List(ctx context.Context, userId int64) ([]Session, error) {
+	query := `
+	SELECT s.id, s.ip_address, s.user_agent, s.created_at, s.last_seen_at
+	FROM sessions s JOIN users u ON u.id = s.user_id
+	WHERE s.user_id = ? AND s.revoked_at IS NULL AND EXISTS (
+		SELECT 1 FROM refresh_tokens r
+		WHERE r.session_id = s.id AND r.used_at IS NULL AND r.revoked_at IS NULL AND r.expires_at > ?
+			AND r.token_version = u.token_version
+	)
+	ORDER BY s.last_seen_at DESC, s.id DESC`
+	rows, err := s.DB.QueryContext(ctx, query, userId, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(&session.ID, &session.IPAddress, &session.UserAgent, &session.CreatedAt, &session.LastSeenAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s SQLSessionStore) Revoke(ctx context.Context, userId, sessionId int64) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	query := "UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL"
+	if err := affectedOne(tx.ExecContext(ctx, query, now, sessionId, userId)); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE session_id = ? AND revoked_at IS NULL", now, sessionId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
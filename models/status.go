@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Status int
+
+// Define enum constants using iota
+const (
+	Pending     Status = iota // 0
+	Accepted    Status = iota // 1
+	Declined    Status = iota // 2
+	Active      Status = iota // 3
+	Deactivated Status = iota // 4
+)
+
+// statusNames is how each status appears in JSON and in the database
+var statusNames = [...]string{"pending", "accepted", "declined", "active", "deactivated"}
+
+func (s Status) IsValid() bool {
+	return s >= Pending && int(s) < len(statusNames)
+}
+
+func (s Status) String() string {
+	if !s.IsValid() {
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+	return statusNames[s]
+}
+
+// ParseStatus looks a status up by its name, ignoring case
+func ParseStatus(name string) (Status, error) {
+	for i, statusName := range statusNames {
+		if strings.EqualFold(statusName, name) {
+			return Status(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownStatus, name)
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("%w %d", ErrUnknownStatus, int(s))
+	}
+	return json.Marshal(statusNames[s])
+}
+
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("%w %s", ErrUnknownStatus, data)
+	}
+	parsed, err := ParseStatus(name)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// Value stores the status by name
+func (s Status) Value() (driver.Value, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("%w %d", ErrUnknownStatus, int(s))
+	}
+	return statusNames[s], nil
+}
+
+// Scan reads a status stored by name
+func (s *Status) Scan(src any) error {
+	var name string
+	switch v := src.(type) {
+	case string:
+		name = v
+	case []byte:
+		name = string(v)
+	default:
+		return fmt.Errorf("%w %v", ErrUnknownStatus, src)
+	}
+	parsed, err := ParseStatus(name)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidTransition is returned when the requested status is not reachable from the current one
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrStatusChanged is returned when another request changed the status first
+	ErrStatusChanged = Conflict("status_changed_concurrently", "Announcement status was changed by another request, please retry")
+	// ErrUnknownStatus is returned when a status name does not match any Status
+	ErrUnknownStatus = errors.New("unknown status")
+)
+
+// statusTransitions lists, for every status, the statuses an admin may move it to
+var statusTransitions = map[Status][]Status{
+	Pending:     {Accepted, Declined},
+	Accepted:    {Active, Declined},
+	Declined:    {Pending},
+	Active:      {Deactivated},
+	Deactivated: {Active},
+}
+
+// AllowedTransitions returns the statuses reachable from s
+func (s Status) AllowedTransitions() []Status {
+	return statusTransitions[s]
+}
+
+// CanTransitionTo reports whether moving from s to next is allowed
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange records one transition of an announcement's status
+type StatusChange struct {
+	ID             int64  `json:"id"`
+	AnnouncementID int64  `json:"announcement_id"`
+	FromStatus     Status `json:"from_status" swaggertype:"string"`
+	ToStatus       Status `json:"to_status" swaggertype:"string"`
+	// ChangedBy is SystemUserID when the application changed the status on its own, such as after too many flags
+	ChangedBy int64     `json:"changed_by"`
+	Reason    string    `json:"reason"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// StatusChangeRequest is the body an admin sends to move an announcement to another status
+type StatusChangeRequest struct {
+	Status *Status `json:"status" binding:"required" swaggertype:"string" enums:"pending,accepted,declined,active,deactivated"`
+	Reason string  `json:"reason"`
+}
+
+// ChangeStatus moves the announcement to next and records who did it and why
+func (a *Announcement) ChangeStatus(ctx context.Context, announcements AnnouncementStore, next Status, changedBy int64, reason string) (*StatusChange, error) {
+	if !a.Status.CanTransitionTo(next) {
+		return nil, ErrInvalidTransition
+	}
+
+	change := StatusChange{
+		AnnouncementID: a.ID,
+		FromStatus:     a.Status,
+		ToStatus:       next,
+		ChangedBy:      changedBy,
+		Reason:         reason,
+		ChangedAt:      time.Now().UTC(),
+	}
+	if err := announcements.ChangeStatus(ctx, &change); err != nil {
+		return nil, err
+	}
+	a.Status = next
+	return &change, nil
+}
@@ -0,0 +1,233 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/helpers"
+)
+
+var (
+	// ErrTwoFactorEnabled is returned when starting an enrolment for a user who already has two-factor authentication
+	ErrTwoFactorEnabled = Conflict("two_factor_already_enabled", "Two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when confirming or disabling two-factor authentication that was not set up
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code does not match
+	ErrInvalidTwoFactorCode = Unauthorized("invalid_two_factor_code", "Invalid two-factor code")
+)
+
+// recoveryCodeCount is the number of one-time recovery codes issued on enrolment
+const recoveryCodeCount = 10
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TOTPCredential is the authenticator app secret of a user
+type TOTPCredential struct {
+	Secret    string
+	Confirmed bool
+	// LastUsedStep is the time step of the last code accepted; neither it nor earlier ones are accepted again
+	LastUsedStep int64
+}
+
+// TwoFactorStore keeps the TOTP secrets and recovery codes of users; recovery codes are only stored hashed
+type TwoFactorStore interface {
+	// Enabled reports whether the user has confirmed a TOTP enrolment
+	Enabled(ctx context.Context, userId int64) (bool, error)
+	// Credential returns the TOTP secret of the user, failing with ErrTwoFactorNotEnabled when there is none
+	Credential(ctx context.Context, userId int64) (*TOTPCredential, error)
+	// SaveSecret stores an unconfirmed secret for the user, replacing any earlier unconfirmed one
+	SaveSecret(ctx context.Context, userId int64, secret string) error
+	// Confirm enables the secret of the user, accepting no code before step, and replaces their recovery codes.
+	// It fails with ErrTwoFactorEnabled when the secret was confirmed already.
+	Confirm(ctx context.Context, userId, step int64, recoveryCodeHashes []string) error
+	// UseStep records that the code of step was used. It fails with ErrInvalidTwoFactorCode unless step is later
+	// than the last one used, so that concurrent use of the same code fails for all but one request.
+	UseStep(ctx context.Context, userId, step int64) error
+	// UseRecoveryCode spends an unused recovery code, failing with ErrInvalidTwoFactorCode when there is none
+	UseRecoveryCode(ctx context.Context, userId int64, codeHash string) error
+	// Disable removes the TOTP secret and the recovery codes of the user
+	Disable(ctx context.Context, userId int64) error
+}
+
+// SQLTwoFactorStore keeps secrets and recovery codes in the totp_credentials and recovery_codes tables of DB
+type SQLTwoFactorStore struct {
+	DB *sql.DB
+}
+
+// BeginTOTPSetup stores a new, unconfirmed secret for the user, replacing any earlier unconfirmed one
+func BeginTOTPSetup(ctx context.Context, twoFactor TwoFactorStore, userId int64) (string, error) {
+	enabled, err := twoFactor.Enabled(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return "", ErrTwoFactorEnabled
+	}
+
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := twoFactor.SaveSecret(ctx, userId, secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their app generates the right codes,
+// and returns the recovery codes, which are only stored hashed
+func ConfirmTOTP(ctx context.Context, twoFactor TwoFactorStore, userId int64, code string) ([]string, error) {
+	credential, err := twoFactor.Credential(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if credential.Confirmed {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	step, ok := helpers.ValidateTOTP(credential.Secret, code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		encoded := hex.EncodeToString(bytes)
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = helpers.HashToken(normalizeRecoveryCode(codes[i]))
+	}
+	if err := twoFactor.Confirm(ctx, userId, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// VerifySecondFactor accepts a current TOTP code, which cannot be used twice, or an unused recovery code, which is then spent
+func VerifySecondFactor(ctx context.Context, twoFactor TwoFactorStore, userId int64, code string) error {
+	credential, err := twoFactor.Credential(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !credential.Confirmed {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := helpers.ValidateTOTP(credential.Secret, strings.TrimSpace(code), time.Now(), credential.LastUsedStep); ok {
+		return twoFactor.UseStep(ctx, userId, step)
+	}
+	return twoFactor.UseRecoveryCode(ctx, userId, helpers.HashToken(normalizeRecoveryCode(code)))
+}
+
+func (s SQLTwoFactorStore) Enabled(ctx context.Context, userId int64) (bool, error) {
+	var enabled bool
+	query := "SELECT EXISTS(SELECT 1 FROM totp_credentials WHERE user_id = ? AND confirmed_at IS NOT NULL)"
+	err := s.DB.QueryRowContext(ctx, query, userId).Scan(&enabled)
+	return enabled, err
+}
+
+func (s SQLTwoFactorStore) Credential(ctx context.Context, userId int64) (*TOTPCredential, error) {
+	var credential TOTPCredential
+	query := "SELECT secret, confirmed_at IS NOT NULL, last_used_step FROM totp_credentials WHERE user_id = ?"
+	err := s.DB.QueryRowContext(ctx, query, userId).Scan(&credential.Secret, &credential.Confirmed, &credential.LastUsedStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (s SQLTwoFactorStore) SaveSecret(ctx context.Context, userId int64, secret string) error {
+	query := `
+	INSERT INTO totp_credentials (user_id, secret, created_at) VALUES (?, ?, ?)
+	ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, created_at = excluded.created_at, last_used_step = 0`
+	_, err := s.DB.ExecContext(ctx, query, userId, secret, time.Now().UTC())
+	return err
+}
+
+func (s SQLTwoFactorStore) Confirm(ctx context.Context, userId, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE totp_credentials SET confirmed_at = ?, last_used_step = ? WHERE user_id = ? AND confirmed_at IS NULL"
+	err = affectedOne(tx.ExecContext(ctx, query, time.Now().UTC(), step, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTwoFactorEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userId)
+	if err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)", userId, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s SQLTwoFactorStore) UseStep(ctx context.Context, userId, step int64) error {
+	query := "UPDATE totp_credentials SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?"
+	err := affectedOne(s.DB.ExecContext(ctx, query, step, userId, step))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+func (s SQLTwoFactorStore) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) error {
+	query := "UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL"
+	err := affectedOne(s.DB.ExecContext(ctx, query, time.Now().UTC(), userId, codeHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+func (s SQLTwoFactorStore) Disable(ctx context.Context, userId int64) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM totp_credentials WHERE user_id = ?", userId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,75 +1,177 @@
 package models
 
 import (
+	"context"
 	"errors"
+	"log"
+	"net/mail"
+	"regexp"
+	"time"
 
-	"github.com/ngirimana/AnnounceIT/db"
 	"github.com/ngirimana/AnnounceIT/helpers"
 )
 
+var (
+	// ErrInvalidCredentials is returned when a password does not match the stored hash
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrPhoneNumberTaken is returned when another account already uses the phone number
+	ErrPhoneNumberTaken = Conflict("phone_number_taken", "Phone number already in use")
+	// ErrEmailTaken is returned when another account already uses the email address
+	ErrEmailTaken = Conflict("email_taken", "Email already in use")
+	// ErrInvalidEmail is returned when an email address is malformed
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidPhoneNumber is returned when a phone number is not in E.164 format
+	ErrInvalidPhoneNumber = errors.New("invalid phone number")
+)
+
+// phoneNumberPattern matches E.164 numbers: a plus sign, a country code and at most 15 digits in all
+var phoneNumberPattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// LoginRequest is the body of a password login
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
 type User struct {
 	ID          int64  `json:"id"`
-	Email       string `json:"email"`
-	Password    string `json:"password"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	PhoneNumber string `json:"phone_number"`
-	Address     string `json:"address"`
-	IsAdmin     bool   `json:"is_admin"`
+	Email       string `json:"email" binding:"required,email_address"`
+	Password    string `json:"password" binding:"required"`
+	FirstName   string `json:"first_name" binding:"required,notblank,max=50"`
+	LastName    string `json:"last_name" binding:"required,notblank,max=50"`
+	PhoneNumber string `json:"phone_number" binding:"required,phone"`
+	Address     string `json:"address" binding:"required,notblank,max=200"`
+	// Roles are granted separately; signup always creates an advertiser
+	Roles []Role `json:"roles"`
+	// TokenVersion is embedded in issued JWTs; bumping it revokes all of them
+	TokenVersion int64 `json:"-"`
+	// VerifiedAt is when the user proved they own the email address; nil until then
+	VerifiedAt *time.Time `json:"verified_at"`
+	// SuspendedAt is set while an admin has suspended the account, which can then not be used at all
+	SuspendedAt      *time.Time `json:"suspended_at"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	// DeletionScheduledAt is when the account will be anonymized, unless the user cancels before then
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	// DeletedAt is when the account was anonymized
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-func (u *User) Save() error {
-
-	query := "INSERT INTO users (first_name, last_name, email, password, phone_number, address, is_admin) VALUES (?, ?, ?, ?, ?, ?, ?)"
-	stmt, err := db.DB.Prepare(query)
-	if err != nil {
-
-		return err
+// ValidateEmail accepts a bare address such as jane@example.com, without a display name
+func ValidateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return ErrInvalidEmail
 	}
+	return nil
+}
 
-	// Always close the statement after it is done being used
-	defer stmt.Close()
+// ValidatePhoneNumber accepts an E.164 number such as +250781234567, without spaces or dashes
+func ValidatePhoneNumber(phone string) error {
+	if !phoneNumberPattern.MatchString(phone) {
+		return ErrInvalidPhoneNumber
+	}
+	return nil
+}
 
-	HashedPassword, err := helpers.HashPassword(u.Password)
+// Save hashes the password and creates the user as an advertiser with an unverified email address;
+// any other role must be granted afterwards
+func (u *User) Save(ctx context.Context, users UserStore) error {
+	hashedPassword, err := helpers.HashPassword(u.Password)
 	if err != nil {
-
 		return err
 	}
+	return users.Create(ctx, u, hashedPassword)
+}
 
-	newUser, err := stmt.Exec(u.FirstName, u.LastName, u.Email, HashedPassword, u.PhoneNumber, u.Address, u.IsAdmin)
+// Authenticate checks the password of the user with the email address and loads the rest of the account
+func (u *User) Authenticate(ctx context.Context, users UserStore) error {
+	found, err := users.GetByEmail(ctx, u.Email)
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+	retrievedPassword, err := users.PasswordHash(ctx, found.ID)
 	if err != nil {
 		return err
 	}
 
-	u.ID, err = newUser.LastInsertId()
-	return err
+	if !helpers.CheckPassword(u.Password, retrievedPassword) {
+		return ErrInvalidCredentials
+	}
+	password := u.Password
+	*u = *found
+	u.Password = password
+	if helpers.PasswordNeedsRehash(retrievedPassword) {
+		u.rehashPassword(ctx, users, retrievedPassword)
+	}
+	return nil
 }
 
-func (u *User) Authenticate() error {
-	query := "SELECT id, password FROM users WHERE email = ?"
-	row := db.DB.QueryRow(query, u.Email)
-
-	var retrievedPassword string
-	err := row.Scan(&u.ID, &retrievedPassword)
+// rehashPassword replaces a hash made with an outdated algorithm or parameters while the password is known.
+// Failing only costs the upgrade, so it does not fail the login.
+func (u *User) rehashPassword(ctx context.Context, users UserStore, oldHash string) {
+	hashedPassword, err := helpers.HashPassword(u.Password)
+	if err == nil {
+		err = users.ReplacePasswordHash(ctx, u.ID, oldHash, hashedPassword)
+	}
 	if err != nil {
-		return errors.New("credentials invalid")
+		log.Printf("Could not rehash the password of user %d: %v", u.ID, err)
 	}
+}
 
-	isPasswordValid := helpers.CheckPassword(u.Password, retrievedPassword)
-	if !isPasswordValid {
-		return errors.New("invalid credentials")
+// RoleNames returns the user's roles as plain strings, the form they take in JWT claims
+func (u *User) RoleNames() []string {
+	names := make([]string, len(u.Roles))
+	for i, role := range u.Roles {
+		names[i] = string(role)
 	}
-	return nil
+	return names
+}
+
+// UserProfileUpdate holds the profile fields a user may change; nil fields are left untouched, but a field that is
+// sent must not be blank
+type UserProfileUpdate struct {
+	FirstName   *string `json:"first_name" binding:"omitempty,notblank,max=50"`
+	LastName    *string `json:"last_name" binding:"omitempty,notblank,max=50"`
+	PhoneNumber *string `json:"phone_number" binding:"omitempty,notblank,phone"`
+	Address     *string `json:"address" binding:"omitempty,notblank,max=200"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
 
+// UpdateProfile applies the update and saves the profile fields
+func (u *User) UpdateProfile(ctx context.Context, users UserStore, p UserProfileUpdate) error {
+	if p.FirstName != nil {
+		u.FirstName = *p.FirstName
+	}
+	if p.LastName != nil {
+		u.LastName = *p.LastName
+	}
+	if p.PhoneNumber != nil {
+		u.PhoneNumber = *p.PhoneNumber
+	}
+	if p.Address != nil {
+		u.Address = *p.Address
+	}
+	return users.UpdateProfile(ctx, u)
 }
 
-func GetUser(email string) (*User, error) {
-	query := "SELECT id, first_name, last_name, email, phone_number, address, is_admin FROM users WHERE email = ?"
-	var user User
-	err := db.DB.QueryRow(query, email).Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.PhoneNumber, &user.Address, &user.IsAdmin)
+// ChangePassword replaces the password after checking the current one, and revokes every JWT issued before
+func (u *User) ChangePassword(ctx context.Context, users UserStore, currentPassword, newPassword string) error {
+	retrievedPassword, err := users.PasswordHash(ctx, u.ID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if !helpers.CheckPassword(currentPassword, retrievedPassword) {
+		return ErrInvalidCredentials
 	}
-	return &user, nil
 
+	hashedPassword, err := helpers.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	u.TokenVersion, err = users.SetPassword(ctx, u.ID, hashedPassword)
+	return err
 }
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAlreadySuspended is returned when suspending an account that is already suspended
+	ErrAlreadySuspended = Conflict("user_already_suspended", "User is already suspended")
+	// ErrNotSuspended is returned when reactivating an account that is not suspended
+	ErrNotSuspended = Conflict("user_not_suspended", "User is not suspended")
+)
+
+// unusablePassword is stored in place of a hash when a password must be reset; no hasher recognizes it
+const unusablePassword = "!"
+
+type SuspendRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// UserFilter narrows a user listing; zero values mean "no constraint"
+type UserFilter struct {
+	// Search matches part of the email, names or phone number
+	Search    string
+	Role      Role
+	Suspended *bool
+	Limit     int
+	Cursor    string
+}
+
+// userCursor marks the last user of a page; users are listed by ID
+type userCursor struct {
+	ID int64 `json:"i"`
+}
+
+func (c userCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(cursor string) (userCursor, error) {
+	var c userCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// normalizeUserFilter applies the default and maximum page size
+func normalizeUserFilter(f UserFilter) UserFilter {
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+	return f
+}
+
+func (s SQLUserStore) List(ctx context.Context, f UserFilter) ([]User, string, error) {
+	f = normalizeUserFilter(f)
+
+	conditions := []string{"1 = 1"}
+	args := []any{}
+	where := func(condition string, values ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if f.Search != "" {
+		// Escape the LIKE wildcards so that they match literally
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(f.Search) + "%"
+		where(`(email LIKE ? ESCAPE '\' OR first_name LIKE ? ESCAPE '\' OR last_name LIKE ? ESCAPE '\' OR phone_number LIKE ? ESCAPE '\')`,
+			pattern, pattern, pattern, pattern)
+	}
+	if f.Role != "" {
+		where("EXISTS (SELECT 1 FROM user_roles WHERE user_id = users.id AND role = ?)", f.Role)
+	}
+	if f.Suspended != nil {
+		if *f.Suspended {
+			where("suspended_at IS NOT NULL")
+		} else {
+			where("suspended_at IS NULL")
+		}
+	}
+	if f.Cursor != "" {
+		cursor, err := decodeUserCursor(f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		where("id > ?", cursor.ID)
+	}
+
+	query := "SELECT " + userColumns + " FROM users WHERE " + strings.Join(conditions, " AND ") + " ORDER BY id LIMIT ?"
+	args = append(args, f.Limit+1)
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		user.Roles, err = queryRoles(ctx, s.DB, user.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	users, nextCursor := userPage(users, f.Limit)
+	return users, nextCursor, nil
+}
+
+// userPage cuts a listing fetched with one user more than the limit down to a page, and returns the cursor of
+// the next page, which is empty on the last page
+func userPage(users []User, limit int) ([]User, string) {
+	if len(users) <= limit {
+		return users, ""
+	}
+	users = users[:limit]
+	return users, userCursor{ID: users[len(users)-1].ID}.encode()
+}
+
+func (s SQLUserStore) IsSuspended(ctx context.Context, id int64) (bool, error) {
+	var suspended bool
+	err := s.DB.QueryRowContext(ctx, "SELECT suspended_at IS NOT NULL FROM users WHERE id = ?", id).Scan(&suspended)
+	return suspended, err
+}
+
+func (s SQLUserStore) Suspend(ctx context.Context, id int64, reason string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE users SET suspended_at = ?, suspension_reason = ? WHERE id = ? AND suspended_at IS NULL"
+	err = affectedOne(tx.ExecContext(ctx, query, time.Now().UTC(), reason, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAlreadySuspended
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := revokeAllTokens(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s SQLUserStore) Reactivate(ctx context.Context, id int64) error {
+	query := "UPDATE users SET suspended_at = NULL, suspension_reason = '' WHERE id = ? AND suspended_at IS NOT NULL"
+	err := affectedOne(s.DB.ExecContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotSuspended
+	}
+	return err
+}
+
+func (s SQLUserStore) ForcePasswordReset(ctx context.Context, id int64) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET password = ? WHERE id = ?", unusablePassword, id)
+	if err != nil {
+		return err
+	}
+	if err := revokeAllTokens(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// UserStore keeps user accounts. Lookups of a user that does not exist fail with sql.ErrNoRows, whatever the
+// implementation, and every method takes the context of the request it serves so that abandoned requests stop querying.
+type UserStore interface {
+	// Create inserts the user with an already hashed password as an advertiser with an unverified email address,
+	// and sets its ID and roles. It fails with ErrEmailTaken or ErrPhoneNumberTaken when another account has them.
+	Create(ctx context.Context, user *User, passwordHash string) error
+	GetByID(ctx context.Context, id int64) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	// PasswordHash returns the stored hash of the user's password, which User never carries
+	PasswordHash(ctx context.Context, id int64) (string, error)
+	// ReplacePasswordHash swaps oldHash for newHash, and leaves a password changed in the meantime alone
+	ReplacePasswordHash(ctx context.Context, id int64, oldHash, newHash string) error
+	// SetPassword stores a new password hash and bumps the token version, which revokes every JWT issued before,
+	// and returns the new version
+	SetPassword(ctx context.Context, id int64, passwordHash string) (int64, error)
+	// UpdateProfile saves the names, phone number and address of the user
+	UpdateProfile(ctx context.Context, user *User) error
+	// VerifyEmail marks the address of the user as verified. It fails with sql.ErrNoRows when the user no longer
+	// has that address; verifying twice keeps the first time.
+	VerifyEmail(ctx context.Context, id int64, email string) error
+	IsEmailVerified(ctx context.Context, id int64) (bool, error)
+	// TokenVersion returns the version a user's JWTs must carry to be accepted
+	TokenVersion(ctx context.Context, id int64) (int64, error)
+
+	// List returns one page of users matching the filter, oldest first, and the cursor of the next page,
+	// which is empty on the last page
+	List(ctx context.Context, f UserFilter) ([]User, string, error)
+
+	// Roles returns the roles granted to a user, sorted by name
+	Roles(ctx context.Context, id int64) ([]Role, error)
+	// GrantRole gives the user a role on behalf of grantedBy; granting a role the user already has is a no-op
+	GrantRole(ctx context.Context, id int64, role Role, grantedBy int64) error
+	// RevokeRole takes a role away from the user. It fails with ErrRoleNotGranted when the user does not have it,
+	// and with ErrLastAdmin rather than leave no admin.
+	RevokeRole(ctx context.Context, id int64, role Role) error
+
+	IsSuspended(ctx context.Context, id int64) (bool, error)
+	// Suspend locks the user out of the account for the reason and revokes every token and session they have.
+	// It fails with ErrAlreadySuspended when the user is suspended already.
+	Suspend(ctx context.Context, id int64, reason string) error
+	// Reactivate lifts the suspension of the user, failing with ErrNotSuspended when there is none; the user
+	// has to log in again
+	Reactivate(ctx context.Context, id int64) error
+	// ForcePasswordReset makes the current password of the user stop working and logs them out everywhere,
+	// so that the only way back in is a password reset
+	ForcePasswordReset(ctx context.Context, id int64) error
+
+	// ScheduleDeletion schedules the account to be anonymized at the given time, failing with ErrDeletionScheduled
+	// when it already is, and with ErrLastAdminDeletion rather than leave no admin
+	ScheduleDeletion(ctx context.Context, id int64, at time.Time) error
+	// CancelDeletion keeps the account after all, failing with ErrNoDeletionScheduled when no deletion is scheduled
+	CancelDeletion(ctx context.Context, id int64) error
+	// DueForDeletion returns the users whose deletion was scheduled for now or earlier
+	DueForDeletion(ctx context.Context, now time.Time) ([]int64, error)
+	// Anonymize erases the personal data of a user and everything that lets anyone sign in as them.
+	// The user itself stays, so that their announcements, kept for broadcast compliance, still have an owner.
+	// It fails with ErrLastAdminDeletion if the user has become the last admin since scheduling the deletion.
+	Anonymize(ctx context.Context, id int64, now time.Time) error
+}
+
+// SQLUserStore keeps users in the users and user_roles tables of DB
+type SQLUserStore struct {
+	DB *sql.DB
+}
+
+func (s SQLUserStore) Create(ctx context.Context, u *User, passwordHash string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO users (first_name, last_name, email, password, phone_number, address) VALUES (?, ?, ?, ?, ?, ?)"
+	newUser, err := tx.ExecContext(ctx, query, u.FirstName, u.LastName, u.Email, passwordHash, u.PhoneNumber, u.Address)
+	if err != nil {
+		return uniqueViolation(err)
+	}
+
+	u.ID, err = newUser.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	query = "INSERT INTO user_roles (user_id, role, granted_by, granted_at) VALUES (?, ?, ?, ?)"
+	_, err = tx.ExecContext(ctx, query, u.ID, RoleAdvertiser, actor(SystemUserID), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	u.Roles = []Role{RoleAdvertiser}
+	u.TokenVersion = 0
+	u.VerifiedAt, u.SuspendedAt, u.SuspensionReason = nil, nil, ""
+	u.DeletionScheduledAt, u.DeletedAt = nil, nil
+	return tx.Commit()
+}
+
+func (s SQLUserStore) GetByID(ctx context.Context, id int64) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
+	return s.getUser(ctx, query, id)
+}
+
+func (s SQLUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = ?"
+	return s.getUser(ctx, query, email)
+}
+
+func (s SQLUserStore) getUser(ctx context.Context, query string, args ...any) (*User, error) {
+	user, err := scanUser(s.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, err
+	}
+	user.Roles, err = queryRoles(ctx, s.DB, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s SQLUserStore) PasswordHash(ctx context.Context, id int64) (string, error) {
+	var passwordHash string
+	err := s.DB.QueryRowContext(ctx, "SELECT password FROM users WHERE id = ?", id).Scan(&passwordHash)
+	return passwordHash, err
+}
+
+func (s SQLUserStore) ReplacePasswordHash(ctx context.Context, id int64, oldHash, newHash string) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE users SET password = ? WHERE id = ? AND password = ?", newHash, id, oldHash)
+	return err
+}
+
+func (s SQLUserStore) SetPassword(ctx context.Context, id int64, passwordHash string) (int64, error) {
+	var tokenVersion int64
+	query := "UPDATE users SET password = ?, token_version = token_version + 1 WHERE id = ? RETURNING token_version"
+	err := s.DB.QueryRowContext(ctx, query, passwordHash, id).Scan(&tokenVersion)
+	return tokenVersion, err
+}
+
+func (s SQLUserStore) UpdateProfile(ctx context.Context, u *User) error {
+	query := "UPDATE users SET first_name = ?, last_name = ?, phone_number = ?, address = ? WHERE id = ?"
+	_, err := s.DB.ExecContext(ctx, query, u.FirstName, u.LastName, u.PhoneNumber, u.Address, u.ID)
+	return uniqueViolation(err)
+}
+
+func (s SQLUserStore) VerifyEmail(ctx context.Context, id int64, email string) error {
+	query := "UPDATE users SET verified_at = COALESCE(verified_at, ?) WHERE id = ? AND email = ?"
+	result, err := s.DB.ExecContext(ctx, query, time.Now().UTC(), id, email)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s SQLUserStore) IsEmailVerified(ctx context.Context, id int64) (bool, error) {
+	var verified bool
+	err := s.DB.QueryRowContext(ctx, "SELECT verified_at IS NOT NULL FROM users WHERE id = ?", id).Scan(&verified)
+	return verified, err
+}
+
+func (s SQLUserStore) TokenVersion(ctx context.Context, id int64) (int64, error) {
+	var tokenVersion int64
+	err := s.DB.QueryRowContext(ctx, "SELECT token_version FROM users WHERE id = ?", id).Scan(&tokenVersion)
+	return tokenVersion, err
+}
+
+const userColumns = `id, first_name, last_name, email, phone_number, address, token_version, verified_at, suspended_at, suspension_reason,
+	deletion_scheduled_at, deleted_at`
+
+// scanUser reads a row selected with userColumns; the roles of the user are loaded separately
+func scanUser(row rowScanner) (*User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.PhoneNumber, &user.Address,
+		&user.TokenVersion, &user.VerifiedAt, &user.SuspendedAt, &user.SuspensionReason, &user.DeletionScheduledAt, &user.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// uniqueViolation translates SQLite unique constraint failures on users into domain errors
+func uniqueViolation(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
+	switch {
+	case strings.Contains(sqliteErr.Error(), "users.phone_number"):
+		return ErrPhoneNumberTaken
+	case strings.Contains(sqliteErr.Error(), "users.email"):
+		return ErrEmailTaken
+	}
+	return err
+}
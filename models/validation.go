@@ -0,0 +1,8 @@
+package models
+
+// FieldError explains why one field of a request body was rejected: Code is for programs, Message for people
+type FieldError struct {
+	Field   string `json:"field" example:"end_date"`
+	Code    string `json:"code" example:"invalid_date_range"`
+	Message string `json:"message" example:"must be after start_date"`
+}
@@ -0,0 +1,104 @@
+package notifications
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(message Message) error
+}
+
+// SMTPMailer sends email through an SMTP server, authenticating when a username is set
+type SMTPMailer struct {
+	// Addr is the host:port of the server
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(message Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{message.To}, format(m.From, message))
+}
+
+// format renders the message with the headers mail servers expect
+func format(from string, message Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// FileMailer appends every message to a file instead of sending it, for development and tests
+type FileMailer struct {
+	Path string
+}
+
+var fileMailerLock sync.Mutex
+
+func (m FileMailer) Send(message Message) error {
+	fileMailerLock.Lock()
+	defer fileMailerLock.Unlock()
+
+	file, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(format("announceit", message), "\r\n\r\n"...))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// MailNotifier sends account messages as email
+type MailNotifier struct {
+	Mailer Mailer
+	// BaseURL is where the API is reachable from the user's mail client, used to build links
+	BaseURL string
+}
+
+func (n MailNotifier) PasswordReset(email, token string) error {
+	return n.Mailer.Send(Message{
+		To:      email,
+		Subject: "Reset your AnnounceIT password",
+		Body:    "Someone asked to reset the password of your AnnounceIT account.\nUse this token to choose a new one:\n\n" + token + "\n\nIf it was not you, ignore this email.",
+	})
+}
+
+func (n MailNotifier) EmailVerification(email, token string) error {
+	link := strings.TrimRight(n.BaseURL, "/") + "/users/verify?token=" + url.QueryEscape(token)
+	return n.Mailer.Send(Message{
+		To:      email,
+		Subject: "Verify your AnnounceIT email address",
+		Body:    "Welcome to AnnounceIT. Open this link to verify your email address:\n\n" + link,
+	})
+}
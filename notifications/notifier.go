@@ -0,0 +1,27 @@
+package notifications
+
+import "log"
+
+// Notifier delivers account messages to users
+type Notifier interface {
+	// PasswordReset sends the single-use token needed to reset the password of email
+	PasswordReset(email, token string) error
+	// EmailVerification sends the token a new user proves they own email with
+	EmailVerification(email, token string) error
+}
+
+// LogNotifier writes messages to the application log; it is meant for development only
+type LogNotifier struct{}
+
+func (LogNotifier) PasswordReset(email, token string) error {
+	log.Printf("Password reset requested for %s, token: %s", email, token)
+	return nil
+}
+
+func (LogNotifier) EmailVerification(email, token string) error {
+	log.Printf("Email verification for %s, token: %s", email, token)
+	return nil
+}
+
+// Default is the notifier used by the controllers; replace it at start-up to deliver messages for real
+var Default Notifier = LogNotifier{}
@@ -4,18 +4,73 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/ngirimana/AnnounceIT/controllers"
 	"github.com/ngirimana/AnnounceIT/middlewares"
+	"github.com/ngirimana/AnnounceIT/models"
 )
 
-func RegisterRoutes(server *gin.Engine) {
+// RegisterRoutes mounts every endpoint on the server, served by handlers that main wires to the stores
+func RegisterRoutes(server *gin.Engine, users *controllers.UserHandler, announcements *controllers.AnnouncementHandler) {
+	server.Use(middlewares.RequestID, middlewares.HandleErrors)
 
-	server.POST("/users/signup", controllers.SignUp)
-	server.POST("/users/login", controllers.Login)
+	server.POST("/users/signup", users.SignUp)
+	server.POST("/users/login", users.Login)
+	server.POST("/users/login/2fa", users.LoginTwoFactor)
+	server.POST("/users/password/forgot", users.ForgotPassword)
+	server.POST("/users/password/reset", users.ResetPassword)
+	server.POST("/users/token/refresh", users.RefreshToken)
+	server.GET("/users/verify", users.VerifyEmail)
 	authenticated := server.Group("/")
 	authenticated.Use(middlewares.Authenticate)
-	authenticated.GET("/users/:email", controllers.GetUser)
-	authenticated.POST("/announcements", controllers.CreateAnnouncement)
 
-	server.GET("/announcements", controllers.GetAnnouncements)
-	server.GET("/announcements/:id", controllers.GetAnnouncement)
+	// Account management is for the user only, never for integrations using an API key
+	account := authenticated.Group("/")
+	account.Use(middlewares.RejectAPIKeys)
+	account.GET("/users/:email", users.GetUser)
+	account.PATCH("/users/me", users.UpdateProfile)
+	account.DELETE("/users/me", users.DeleteAccount)
+	account.POST("/users/me/deletion/cancel", users.CancelAccountDeletion)
+	account.GET("/users/me/export", users.ExportAccount)
+	account.PUT("/users/me/password", users.ChangePassword)
+	account.POST("/users/me/verification", users.ResendVerification)
+	account.POST("/users/logout", users.Logout)
+	account.POST("/users/logout-all", users.LogoutAll)
+	account.POST("/users/me/api-keys", users.CreateAPIKey)
+	account.GET("/users/me/api-keys", users.GetAPIKeys)
+	account.DELETE("/users/me/api-keys/:id", users.RevokeAPIKey)
+	account.GET("/users/me/sessions", users.GetSessions)
+	account.DELETE("/users/me/sessions/:id", users.RevokeSession)
+	account.POST("/users/me/2fa/setup", users.SetupTwoFactor)
+	account.POST("/users/me/2fa/confirm", users.ConfirmTwoFactor)
+	account.DELETE("/users/me/2fa", users.DisableTwoFactor)
+
+	can := middlewares.RequirePermission
+	account.POST("/announcements/:id/flags", can(models.PermCreateFlags), announcements.FlagAnnouncement)
+	authenticated.GET("/users/me/announcements", can(models.PermReadAnnouncements), announcements.GetMyAnnouncements)
+	authenticated.POST("/announcements", can(models.PermCreateAnnouncements), announcements.CreateAnnouncement)
+	authenticated.PUT("/announcements/:id", can(models.PermCreateAnnouncements), announcements.UpdateAnnouncement)
+	authenticated.PATCH("/announcements/:id", can(models.PermCreateAnnouncements), announcements.UpdateAnnouncement)
+	authenticated.GET("/announcements/deleted", can(models.PermReadAllAnnouncements), announcements.GetDeletedAnnouncements)
+	authenticated.GET("/announcements/:id/status/history", can(models.PermReadAllAnnouncements), announcements.GetAnnouncementStatusHistory)
+	authenticated.PATCH("/announcements/:id/status", can(models.PermModerateAnnouncements), announcements.ChangeAnnouncementStatus)
+	authenticated.DELETE("/announcements/:id", can(models.PermDeleteAnnouncements), announcements.DeleteAnnouncement)
+	authenticated.POST("/announcements/:id/restore", can(models.PermDeleteAnnouncements), announcements.RestoreAnnouncement)
+	authenticated.GET("/flags", can(models.PermReadFlags), announcements.GetFlags)
+	authenticated.GET("/flags/counts", can(models.PermReadFlags), announcements.GetFlagCounts)
+	authenticated.PATCH("/flags/:id", can(models.PermResolveFlags), announcements.ResolveFlag)
+	authenticated.GET("/blacklist", can(models.PermReadBlacklist), users.GetBlacklistChanges)
+	authenticated.POST("/users/:id/blacklist", can(models.PermManageBlacklist), users.BlacklistUser)
+	authenticated.DELETE("/users/:id/blacklist", can(models.PermManageBlacklist), users.UnblacklistUser)
+	authenticated.POST("/users/:id/roles", can(models.PermManageRoles), users.GrantRole)
+	authenticated.DELETE("/users/:id/roles/:role", can(models.PermManageRoles), users.RevokeRole)
+	authenticated.POST("/users/:id/unlock", can(models.PermUnlockUsers), users.UnlockUser)
+	authenticated.GET("/admin/users", can(models.PermReadUsers), users.ListUsers)
+	authenticated.GET("/admin/users/:id", can(models.PermReadUsers), users.GetUserByID)
+	authenticated.GET("/admin/users/:id/announcements", can(models.PermReadUsers), users.GetUserAnnouncements)
+	authenticated.GET("/admin/users/:id/flags", can(models.PermReadUsers), users.GetUserFlags)
+	authenticated.POST("/admin/users/:id/suspend", can(models.PermManageUsers), users.SuspendUser)
+	authenticated.POST("/admin/users/:id/reactivate", can(models.PermManageUsers), users.ReactivateUser)
+	authenticated.POST("/admin/users/:id/password-reset", can(models.PermManageUsers), users.ForcePasswordReset)
+
+	server.GET("/announcements", middlewares.OptionalAuthenticate, announcements.GetAnnouncements)
+	server.GET("/announcements/:id", middlewares.OptionalAuthenticate, announcements.GetAnnouncement)
 
 }
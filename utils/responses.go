@@ -1,19 +1,35 @@
 package utils
 
-import "github.com/ngirimana/AnnounceIT/models"
+import (
+	"time"
+
+	"github.com/ngirimana/AnnounceIT/models"
+)
 
 type UserSuccessResponse struct {
 	Message string      `json:"message"` // The success message
 	Data    models.User `json:"user"`    // The user data
 }
 
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the RFC 7807 problem every error is answered with, as application/problem+json
 type ErrorResponse struct {
-	Error string `json:"error"` // The error message
+	Type      string `json:"type" example:"about:blank"`
+	Title     string `json:"title" example:"Not Found"` // The reason phrase of the status
+	Status    int    `json:"status" example:"404"`
+	Detail    string `json:"detail" example:"Announcement not found"` // The error message, for humans
+	Instance  string `json:"instance" example:"/announcements/42"`    // The path of the request
+	Code      string `json:"code" example:"announcement_not_found"`   // Identifies the error for programs and does not change
+	RequestID string `json:"request_id" example:"4f9c1e0a7b3d4c2e9a8b6d5f1e2c3b4a"`
 }
 
 type LoginSuccessResponse struct {
-	Token   string `json:"token"`
-	Message string `json:"message"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	Message      string `json:"message"`
 }
 
 type LoginData struct {
@@ -25,3 +41,92 @@ type AnnouncementSuccessResponse struct {
 	Message string              `json:"message"`
 	Data    models.Announcement `json:"announcement"`
 }
+
+type AnnouncementListResponse struct {
+	Message       string                `json:"message"`
+	Announcements []models.Announcement `json:"announcements"`
+	NextCursor    *string               `json:"next_cursor"`
+}
+
+type StatusChangeSuccessResponse struct {
+	Message      string              `json:"message"`
+	Announcement models.Announcement `json:"announcement"`
+	Change       models.StatusChange `json:"change"`
+}
+
+type StatusTransitionErrorResponse struct {
+	ErrorResponse
+	Allowed []string `json:"allowed" example:"accepted,declined"`
+}
+
+type FlagSuccessResponse struct {
+	Message string      `json:"message"`
+	Flag    models.Flag `json:"flag"`
+}
+
+type BlacklistSuccessResponse struct {
+	Message string                 `json:"message"`
+	Change  models.BlacklistChange `json:"change"`
+}
+
+type BlacklistedErrorResponse struct {
+	ErrorResponse
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type RolesSuccessResponse struct {
+	Message string        `json:"message"`
+	UserID  int64         `json:"user_id"`
+	Roles   []models.Role `json:"roles" swaggertype:"array,string" enums:"advertiser,moderator,admin,auditor"`
+}
+
+type APIKeySuccessResponse struct {
+	Message string        `json:"message"`
+	Key     string        `json:"key"`
+	APIKey  models.APIKey `json:"api_key"`
+}
+
+type RetryAfterErrorResponse struct {
+	ErrorResponse
+	RetryAfter int `json:"retry_after"`
+}
+
+type TwoFactorChallengeResponse struct {
+	Message           string `json:"message"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+type TwoFactorSetupResponse struct {
+	Message    string `json:"message"`
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type UserListResponse struct {
+	Message    string        `json:"message"`
+	Users      []models.User `json:"users"`
+	NextCursor *string       `json:"next_cursor"`
+}
+
+type UserFlagsResponse struct {
+	Message  string        `json:"message"`
+	Filed    []models.Flag `json:"filed"`
+	Received []models.Flag `json:"received"`
+}
+
+type AccountDeletionResponse struct {
+	Message             string    `json:"message"`
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+type ValidationErrorResponse struct {
+	ErrorResponse
+	Fields []models.FieldError `json:"fields"`
+}